@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"go_scripts/database"
+	appErr "go_scripts/internal/errors"
+	"go_scripts/internal/logger"
+	"go_scripts/telegraph"
+)
+
+// maxAccountSwitches — сколько раз подряд можно сменить аккаунт из-за FLOOD_WAIT
+// при создании одной работы.
+const maxAccountSwitches = 5
+
+// accountPool раздаёт аккаунты Telegraph из таблицы telegraph_accounts.
+type accountPool struct {
+	base *telegraph.Client
+	// legacyToken (ACCESS_TOKEN) — владелец страниц, созданных до появления пула
+	legacyToken string
+	store       accountStore // nil — dbAccounts
+}
+
+// accountStore — состояние пула: выбор аккаунта по кругу, FLOOD_WAIT и
+// счётчик страниц.
+type accountStore interface {
+	Next() (*database.TelegraphAccount, *time.Time, error)
+	Flood(id uint, until time.Time, errMsg string) error
+	AddPages(id uint, n int) error
+}
+
+// dbAccounts — accountStore поверх таблицы telegraph_accounts.
+type dbAccounts struct{}
+
+func (dbAccounts) Next() (*database.TelegraphAccount, *time.Time, error) {
+	return database.TelegraphAccountNext()
+}
+
+func (dbAccounts) Flood(id uint, until time.Time, errMsg string) error {
+	return database.TelegraphAccountFlood(id, until, errMsg)
+}
+
+func (dbAccounts) AddPages(id uint, n int) error { return database.TelegraphAccountAddPages(id, n) }
+
+func (a *accountPool) accounts() accountStore {
+	if a.store == nil {
+		return dbAccounts{}
+	}
+	return a.store
+}
+
+// seed добавляет ACCESS_TOKEN в пул, чтобы одиночная конфигурация работала
+// как пул из одного аккаунта.
+func (a *accountPool) seed(authorName, authorURL string) error {
+	if a.legacyToken == "" {
+		return nil
+	}
+	return database.TelegraphAccountAdd(&database.TelegraphAccount{AccessToken: a.legacyToken, AuthorName: authorName, AuthorURL: authorURL})
+}
+
+// create выполняет fn от имени следующего по кругу аккаунта. При FLOOD_WAIT
+// аккаунт откладывается на указанное в ошибке время, а работа создаётся
+// заново другим аккаунтом: все части одной работы должны принадлежать одному
+// аккаунту. Части, которые fn успела создать до ошибки, остаются
+// неиспользованными — они учитываются в счётчике страниц аккаунта и пишутся
+// в лог.
+func (a *accountPool) create(ctx context.Context, fn func(*telegraph.Client) ([]string, error)) ([]string, uint, error) {
+	for i := 0; ; i++ {
+		acc, until, err := a.accounts().Next()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			wait := 30 * time.Second
+			if until != nil && time.Until(*until) > 0 {
+				wait = time.Until(*until)
+			}
+			return nil, 0, appErr.NewTelegramError("все аккаунты Telegraph ждут FLOOD_WAIT", nil).WithRetryAfter(wait)
+		}
+		if errors.Is(err, database.ErrNoTelegraphAccount) {
+			return nil, 0, appErr.NewInternalError("пул аккаунтов Telegraph пуст", err)
+		}
+		if err != nil {
+			return nil, 0, appErr.NewDatabaseError("pick telegraph account", err).WithTransient(true)
+		}
+
+		urls, err := fn(a.client(acc))
+		if err != nil {
+			a.orphaned(acc, urls)
+			if a.flooded(acc, err) && i < maxAccountSwitches && ctx.Err() == nil {
+				continue
+			}
+			return nil, acc.ID, err
+		}
+		a.addPages(acc, len(urls))
+		return urls, acc.ID, nil
+	}
+}
+
+// orphaned учитывает страницы, которые аккаунт создал для неудавшейся
+// операции: удалить их нельзя, поэтому они остаются в счётчике и в логе.
+func (a *accountPool) orphaned(acc *database.TelegraphAccount, urls []string) {
+	if len(urls) == 0 {
+		return
+	}
+	a.addPages(acc, len(urls))
+	logger.Warn("PROCESSOR", "telegraph account id=%d left %d orphaned parts: %s", acc.ID, len(urls), strings.Join(urls, " "))
+}
+
+// addPages учитывает n новых страниц аккаунта.
+func (a *accountPool) addPages(acc *database.TelegraphAccount, n int) {
+	if err := a.accounts().AddPages(acc.ID, n); err != nil {
+		logger.DatabaseError("telegraph account %d page count: %v", acc.ID, err)
+	}
+}
+
+// owner возвращает аккаунт, создавший страницы записи: редактировать их
+// может только он. Если аккаунт ждёт FLOOD_WAIT, ошибка временная.
+func (a *accountPool) owner(content *database.Content) (*database.TelegraphAccount, *telegraph.Client, error) {
+	var acc *database.TelegraphAccount
+	var err error
+	switch {
+	case content.TelegraphAccountID != nil:
+		acc, err = database.TelegraphAccountGet(*content.TelegraphAccountID)
+	case a.legacyToken != "":
+		acc, err = database.TelegraphAccountGetByToken(a.legacyToken)
+	default:
+		return nil, nil, appErr.NewInternalError("неизвестен аккаунт Telegraph, создавший страницы", nil)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, appErr.NewInternalError("аккаунт Telegraph, создавший страницы, удалён из пула", err)
+	}
+	if err != nil {
+		return nil, nil, appErr.NewDatabaseError("load telegraph account", err).WithTransient(true)
+	}
+	if acc.FloodUntil != nil && time.Until(*acc.FloodUntil) > 0 {
+		return nil, nil, appErr.NewTelegramError("аккаунт Telegraph ждёт FLOOD_WAIT", nil).WithRetryAfter(time.Until(*acc.FloodUntil))
+	}
+	return acc, a.client(acc), nil
+}
+
+func (a *accountPool) client(acc *database.TelegraphAccount) *telegraph.Client {
+	return a.base.WithAccount(acc.AccessToken, acc.AuthorName, acc.AuthorURL)
+}
+
+// flooded откладывает аккаунт, если err — FLOOD_WAIT, и сообщает об этом.
+func (a *accountPool) flooded(acc *database.TelegraphAccount, err error) bool {
+	var ae *appErr.AppError
+	if !errors.As(err, &ae) || !strings.HasPrefix(ae.Code, "FLOOD_WAIT") {
+		return false
+	}
+	wait := ae.RetryAfter
+	if wait <= 0 {
+		wait = 30 * time.Second
+	}
+	if dbErr := a.accounts().Flood(acc.ID, time.Now().Add(wait), ae.Code); dbErr != nil {
+		logger.DatabaseError("telegraph account %d flood: %v", acc.ID, dbErr)
+	}
+	logger.Warn("PROCESSOR", "telegraph account id=%d hit %s, pausing for %s", acc.ID, ae.Code, wait)
+	return true
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"go_scripts/database"
+	appErr "go_scripts/internal/errors"
+	"go_scripts/telegraph"
+)
+
+// memAccounts — accountStore в памяти с той же политикой выбора, что у
+// TelegraphAccountNext: дольше всех не использовавшийся аккаунт без FLOOD_WAIT.
+type memAccounts struct {
+	rows  []*database.TelegraphAccount
+	clock time.Time // last_used_at растёт монотонно, чтобы порядок не зависел от разрешения часов
+}
+
+func newMemAccounts(tokens ...string) *memAccounts {
+	m := &memAccounts{clock: time.Now()}
+	for i, t := range tokens {
+		m.rows = append(m.rows, &database.TelegraphAccount{ID: uint(i + 1), AccessToken: t, Enabled: true})
+	}
+	return m
+}
+
+func (m *memAccounts) Next() (*database.TelegraphAccount, *time.Time, error) {
+	var free []*database.TelegraphAccount
+	var soonest *time.Time
+	for _, a := range m.rows {
+		if a.FloodUntil != nil && a.FloodUntil.After(time.Now()) {
+			if soonest == nil || a.FloodUntil.Before(*soonest) {
+				soonest = a.FloodUntil
+			}
+			continue
+		}
+		free = append(free, a)
+	}
+	if len(free) == 0 {
+		return nil, soonest, gorm.ErrRecordNotFound
+	}
+	sort.SliceStable(free, func(i, j int) bool {
+		li, lj := free[i].LastUsedAt, free[j].LastUsedAt
+		switch {
+		case li == nil || lj == nil:
+			return li == nil && lj != nil
+		default:
+			return li.Before(*lj)
+		}
+	})
+	a := free[0]
+	m.clock = m.clock.Add(time.Millisecond)
+	used := m.clock
+	a.LastUsedAt = &used
+	return a, nil, nil
+}
+
+func (m *memAccounts) Flood(id uint, until time.Time, errMsg string) error {
+	a := m.rows[id-1]
+	a.FloodUntil, a.LastError = &until, errMsg
+	return nil
+}
+
+func (m *memAccounts) AddPages(id uint, n int) error {
+	if n > 0 {
+		m.rows[id-1].PageCount += n
+	}
+	return nil
+}
+
+func floodWait(secs int) error {
+	return appErr.NewTelegramError("telegraph API error: FLOOD_WAIT", nil).
+		WithCode("FLOOD_WAIT_" + strconv.Itoa(secs)).
+		WithRetryAfter(time.Duration(secs) * time.Second)
+}
+
+func newTestPool(store *memAccounts) *accountPool {
+	return &accountPool{base: telegraph.NewClient(telegraph.Config{BaseURL: "http://telegraph.invalid"}), store: store}
+}
+
+func TestAccountPoolRoundRobin(t *testing.T) {
+	store := newMemAccounts("a", "b", "c")
+	pool := newTestPool(store)
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		_, id, err := pool.create(context.Background(), func(c *telegraph.Client) ([]string, error) {
+			got = append(got, c.AccessToken())
+			return []string{"https://telegra.ph/p"}, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := uint(i%3 + 1); id != want {
+			t.Fatalf("call %d: account %d, want %d", i, id, want)
+		}
+	}
+	if want := []string{"a", "b", "c", "a"}; !slices.Equal(got, want) {
+		t.Fatalf("tokens = %v, want %v", got, want)
+	}
+	if store.rows[0].PageCount != 2 || store.rows[1].PageCount != 1 {
+		t.Fatalf("page counts = %d, %d", store.rows[0].PageCount, store.rows[1].PageCount)
+	}
+}
+
+func TestAccountPoolSwitchesOnFloodWait(t *testing.T) {
+	store := newMemAccounts("a", "b")
+	pool := newTestPool(store)
+
+	var tried []string
+	urls, id, err := pool.create(context.Background(), func(c *telegraph.Client) ([]string, error) {
+		tried = append(tried, c.AccessToken())
+		if c.AccessToken() == "a" {
+			// первая часть успела создаться до FLOOD_WAIT
+			return []string{"https://telegra.ph/orphan"}, floodWait(60)
+		}
+		return []string{"https://telegra.ph/ok", "https://telegra.ph/ok-2"}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 2 || len(urls) != 2 || !slices.Equal(tried, []string{"a", "b"}) {
+		t.Fatalf("id = %d, urls = %v, tried = %v", id, urls, tried)
+	}
+	a := store.rows[0]
+	if a.FloodUntil == nil || time.Until(*a.FloodUntil) < 50*time.Second {
+		t.Fatalf("flooded account not backed off: %v", a.FloodUntil)
+	}
+	if a.PageCount != 1 || store.rows[1].PageCount != 2 {
+		t.Fatalf("page counts = %d, %d; orphaned part must be counted", a.PageCount, store.rows[1].PageCount)
+	}
+}
+
+func TestAccountPoolAllFlooded(t *testing.T) {
+	store := newMemAccounts("a", "b")
+	pool := newTestPool(store)
+
+	calls := 0
+	_, _, err := pool.create(context.Background(), func(c *telegraph.Client) ([]string, error) {
+		calls++
+		return nil, floodWait(30 * calls)
+	})
+	if calls != 2 {
+		t.Fatalf("fn called %d times, want one per account", calls)
+	}
+	if !appErr.IsTransient(err) {
+		t.Fatalf("want transient error, got %v", err)
+	}
+	// ближайший освободится аккаунт "a" через ~30 секунд
+	if ra := appErr.RetryAfterOf(err); ra < 25*time.Second || ra > 30*time.Second {
+		t.Fatalf("retry after = %s, want ~30s", ra)
+	}
+}
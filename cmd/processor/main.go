@@ -1,14 +1,19 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"fmt"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/joho/godotenv"
 
 	"go_scripts/config"
 	"go_scripts/database"
+	"go_scripts/images"
 	"go_scripts/internal/logger"
 	"go_scripts/parsers"
 	"go_scripts/telegraph"
@@ -26,34 +31,181 @@ func main() {
 		logger.DatabaseError("init db: %v", err)
 		os.Exit(1)
 	}
+	fetcher, err := parsers.NewFetcher(httpOptions(cfg))
+	if err != nil {
+		logger.Error("PROCESSOR", "http client: %v", err)
+		os.Exit(1)
+	}
+	parsers.SetDefaultFetcher(fetcher)
+	rules, err := parsers.RegisterRulesDir(cfg.ParserRulesDir)
+	if err != nil {
+		logger.Error("PROCESSOR", "parser rules: %v", err)
+		os.Exit(1)
+	}
+	for _, r := range rules {
+		logger.Info("PROCESSOR", "loaded parser rule name=%s hosts=%v", r.Name(), r.Hosts())
+	}
+	if err := parsers.ConfigureHentaichan(cfg.HentaichanSelectors); err != nil {
+		logger.Error("PROCESSOR", "hentaichan selectors: %v", err)
+		os.Exit(1)
+	}
 
-	for {
-		content, err := database.ContentClaimNew()
-		if err != nil {
-			time.Sleep(2 * time.Second)
-			continue
-		}
-		start := time.Now()
-		logger.Info("PROCESSOR", "processing url=%s", content.UrlHentaichan)
-		info, err := parsers.HentaichanParseAll(content.UrlHentaichan)
-		if err != nil {
-			_ = database.ContentMarkError(content.ID, err.Error())
-			logger.Error("PROCESSOR", "error parsing url=%s: %v", content.UrlHentaichan, err)
-			continue
-		}
-		// store meta (series, author, translator, tags)
-		tagsJSONBytes, _ := json.Marshal(info.Tags)
-		_ = database.ContentUpdateMeta(content.ID, info.Title, info.Series, info.Author, info.Translator, string(tagsJSONBytes))
-		logger.Info("PROCESSOR", "parsed url=%s, title=%s, series=%s, author=%s, translator=%s, tags=%v, images=%d", content.UrlHentaichan, info.Title, info.Series, info.Author, info.Translator, info.Tags, len(info.ImageURLs))
-		url, err := telegraph.CreateTelegraphPage(info.Title, info.ImageURLs)
-		if err != nil {
-			_ = database.ContentMarkError(content.ID, err.Error())
-			logger.Error("PROCESSOR", "error creating telegraph page: %v", err)
-			continue
+	tgph := telegraph.NewClient(telegraph.Config{
+		BaseURL:     cfg.TelegraphAPI,
+		UploadURL:   cfg.TelegraphUploadURL,
+		AccessToken: cfg.TelegraphAccessToken,
+		AuthorName:  cfg.TelegraphAuthorName,
+		AuthorURL:   cfg.TelegraphAuthorURL,
+		Timeout:     cfg.TelegraphTimeout,
+	})
+	accounts := &accountPool{base: tgph, legacyToken: cfg.TelegraphAccessToken}
+	if err := accounts.seed(cfg.TelegraphAuthorName, cfg.TelegraphAuthorURL); err != nil {
+		logger.DatabaseError("seed telegraph account: %v", err)
+		os.Exit(1)
+	}
+	if n, err := enabledAccounts(); err != nil {
+		logger.DatabaseError("telegraph accounts: %v", err)
+		os.Exit(1)
+	} else if n == 0 {
+		logger.Error("PROCESSOR", "no Telegraph accounts; add one with: go run ./cmd/telegraph-account pool-add -short-name niko (or set ACCESS_TOKEN)")
+		os.Exit(1)
+	} else {
+		logger.Info("PROCESSOR", "telegraph accounts in pool: %d", n)
+	}
+	store, err := images.NewStore(cfg.ImageStore, tgph, cfg.ImageStoreDir, cfg.ImageStoreBaseURL)
+	if err != nil {
+		logger.Error("PROCESSOR", "image store: %v", err)
+		os.Exit(1)
+	}
+	var rehost *images.Rehoster
+	if store != nil {
+		target := imageTarget(cfg, store.Name())
+		rehost = &images.Rehoster{Fetcher: fetcher, Store: store, Target: target, MaxAttempts: cfg.ImageMaxAttempts, RetryBase: cfg.ImageRetryBase}
+		logger.Info("PROCESSOR", "re-hosting images to store=%s formats=%v max=%dx%d bytes=%d", store.Name(), target.Formats, target.MaxWidth, target.MaxHeight, target.MaxBytes)
+	}
+
+	// SIGINT/SIGTERM stops claiming new rows; in-flight items get
+	// ProcessorDrainTimeout to finish before their fetches are aborted.
+	runCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	workCtx, cancelWork := context.WithCancel(context.Background())
+	defer cancelWork()
+
+	p := &processor{
+		maxAttempts: cfg.ProcessorMaxAttempts,
+		retryBase:   cfg.ProcessorRetryBase,
+		retryMax:    cfg.ProcessorRetryMax,
+		lease:       cfg.ProcessorLease,
+		accounts:    accounts,
+		split:       telegraph.SplitOptions{MaxImages: cfg.TelegraphMaxImagesPerPage, MaxBytes: cfg.TelegraphMaxPageBytes},
+		layout:      pageLayout(cfg),
+
+		rehost:           rehost,
+		imageConcurrency: cfg.ImageConcurrency,
+	}
+	go p.reaper(runCtx, cfg.ProcessorReaperInterval)
+
+	instance := instanceID()
+	var wg sync.WaitGroup
+	for i := 1; i <= cfg.ProcessorWorkers; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			p.worker(runCtx, workCtx, fmt.Sprintf("%s/%d", instance, id))
+		}(i)
+	}
+	logger.Info("PROCESSOR", "started workers=%d", cfg.ProcessorWorkers)
+
+	<-runCtx.Done()
+	logger.Info("PROCESSOR", "shutting down, draining in-flight items (timeout %s)", cfg.ProcessorDrainTimeout)
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(cfg.ProcessorDrainTimeout):
+		logger.Warn("PROCESSOR", "drain timeout, aborting in-flight items")
+		cancelWork()
+		<-done
+	}
+	logger.Info("PROCESSOR", "processor stopped")
+}
+
+// instanceID identifies this processor replica in contents.claimed_by.
+func instanceID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "processor"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+func httpOptions(cfg *config.Config) parsers.HTTPOptions {
+	o := parsers.DefaultHTTPOptions()
+	o.Timeout = cfg.ParserTimeout
+	o.ConnectTimeout = cfg.ParserConnectTimeout
+	if cfg.ParserUserAgent != "" {
+		o.UserAgent = cfg.ParserUserAgent
+	}
+	for k, v := range cfg.ParserHeaders {
+		o.Headers[k] = v
+	}
+	o.ProxyURL = cfg.ParserProxyURL
+	o.CookieJar = cfg.ParserCookieJar
+	o.Politeness.RatePerSec = cfg.ParserRatePerSec
+	o.Politeness.Burst = cfg.ParserBurst
+	o.Politeness.MaxConcurrent = cfg.ParserMaxConcurrentPerHost
+	o.Politeness.MinDelay = cfg.ParserMinDelay
+	o.Politeness.MaxDelay = cfg.ParserMaxDelay
+	o.Politeness.MaxRetries = cfg.ParserMaxRetries
+	return o
+}
+
+func enabledAccounts() (int, error) {
+	accs, err := database.TelegraphAccountList()
+	n := 0
+	for _, a := range accs {
+		if a.Enabled {
+			n++
 		}
-		logger.Info("PROCESSOR", "created telegraph page url=%s", url)
-		_ = database.ContentMarkParsed(content.ID, url)
-		logger.Info("PROCESSOR", "marked parsed url=%s", content.UrlHentaichan)
-		logger.Info("PROCESSOR", "processed url elapsed=%s", time.Since(start))
 	}
+	return n, err
+}
+
+// pageLayout — общие для всех страниц поля оформления или nil для TELEGRAPH_LAYOUT=plain.
+func pageLayout(cfg *config.Config) *telegraph.PageMeta {
+	if cfg.TelegraphLayout == "plain" {
+		return nil
+	}
+	return &telegraph.PageMeta{
+		TagURL:        cfg.TelegraphTagURL,
+		SubscribeURL:  cfg.SubscribeLinkURL,
+		SubscribeText: cfg.TelegraphSubscribeText,
+	}
+}
+
+// imageTarget — требования хранилища по умолчанию с переопределениями из IMAGE_*.
+func imageTarget(cfg *config.Config, store string) images.Target {
+	t := images.DefaultTarget(store)
+	if len(cfg.ImageFormats) > 0 {
+		t.Formats = cfg.ImageFormats
+	}
+	if cfg.ImageMaxWidth > 0 {
+		t.MaxWidth = cfg.ImageMaxWidth
+	}
+	if cfg.ImageMaxHeight > 0 {
+		t.MaxHeight = cfg.ImageMaxHeight
+	}
+	if cfg.ImageMaxBytes > 0 {
+		t.MaxBytes = cfg.ImageMaxBytes
+	}
+	if cfg.ImageJPEGQuality > 0 {
+		t.JPEGQuality = cfg.ImageJPEGQuality
+	}
+	if cfg.ImageKeepMetadata {
+		t.StripMetadata = false
+	}
+	return t
 }
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"go_scripts/database"
+	"go_scripts/images"
+	appErr "go_scripts/internal/errors"
+	"go_scripts/internal/logger"
+	"go_scripts/parsers"
+	"go_scripts/telegraph"
+)
+
+type processor struct {
+	maxAttempts int
+	retryBase   time.Duration
+	retryMax    time.Duration
+	lease       time.Duration
+	accounts    *accountPool
+	split       telegraph.SplitOptions
+	layout      *telegraph.PageMeta // общие поля оформления; nil — только изображения
+
+	rehost           *images.Rehoster // nil — ссылаться на изображения источника
+	imageConcurrency int
+}
+
+// worker claims rows until runCtx is cancelled; items are processed with
+// workCtx so a shutdown lets the current item finish. New rows go first,
+// re-parse requests are picked up when there is nothing new.
+func (p *processor) worker(runCtx, workCtx context.Context, claimer string) {
+	for runCtx.Err() == nil {
+		content, err := database.ContentClaimNew(claimer, p.lease)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if c, rerr := database.ContentClaimReparse(claimer, p.lease); rerr == nil {
+				p.leased(workCtx, c.ID, claimer, database.ContentReparseHeartbeat, func(ctx context.Context) {
+					p.reparse(ctx, c, claimer)
+				})
+				continue
+			} else if !errors.Is(rerr, gorm.ErrRecordNotFound) {
+				logger.DatabaseError("worker=%s claim reparse: %v", claimer, rerr)
+			}
+		}
+		if err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				logger.DatabaseError("worker=%s claim: %v", claimer, err)
+			}
+			sleepCtx(runCtx, 2*time.Second)
+			continue
+		}
+		p.leased(workCtx, content.ID, claimer, database.ContentHeartbeat, func(ctx context.Context) {
+			p.process(ctx, content, claimer)
+		})
+	}
+}
+
+// beatFunc extends claimer's lease on row id; ErrLeaseLost means the row
+// was taken away.
+type beatFunc func(id uint, claimer string, lease time.Duration) error
+
+// leased runs fn with a heartbeat goroutine that keeps the lease alive and
+// cancels fn's context if the lease is lost.
+func (p *processor) leased(ctx context.Context, id uint, claimer string, beat beatFunc, fn func(ctx context.Context)) {
+	itemCtx, cancel := context.WithCancel(ctx)
+	hbDone := make(chan struct{})
+	go func() {
+		defer close(hbDone)
+		p.heartbeat(itemCtx, cancel, id, claimer, beat)
+	}()
+	fn(itemCtx)
+	cancel()
+	<-hbDone
+}
+
+// heartbeat extends the lease every lease/3 while the item is processed and
+// cancels the item if the lease was lost (reaped after a stall).
+func (p *processor) heartbeat(ctx context.Context, cancel context.CancelFunc, id uint, claimer string, beat beatFunc) {
+	t := time.NewTicker(p.lease / 3)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			err := beat(id, claimer, p.lease)
+			if errors.Is(err, database.ErrLeaseLost) {
+				logger.Warn("PROCESSOR", "worker=%s lost lease on id=%d, aborting", claimer, id)
+				cancel()
+				return
+			}
+			if err != nil {
+				logger.DatabaseError("worker=%s heartbeat id=%d: %v", claimer, id, err)
+			}
+		}
+	}
+}
+
+// reaper periodically returns rows with expired leases to New.
+func (p *processor) reaper(ctx context.Context, interval time.Duration) {
+	for {
+		if n, err := database.ContentReapExpiredLeases(p.maxAttempts); err != nil {
+			logger.DatabaseError("reap expired leases: %v", err)
+		} else if n > 0 {
+			logger.Warn("PROCESSOR", "reaped %d rows with expired leases", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (p *processor) process(ctx context.Context, content *database.Content, claimer string) {
+	start := time.Now()
+	logger.Info("PROCESSOR", "processing url=%s attempt=%d", content.SourceURL, content.Attempts+1)
+	parser, err := parsers.Lookup(content.SourceURL)
+	if err != nil {
+		if settle(content, claimer, database.ContentMarkClaimError(content.ID, err.Error(), claimer)) {
+			logger.Error("PROCESSOR", "no parser for url=%s: %v", content.SourceURL, err)
+		}
+		return
+	}
+	work, err := parser.Parse(ctx, content.SourceURL)
+	if err != nil {
+		if ctx.Err() != nil {
+			// interrupted by shutdown or lost lease, not a parse failure: give the row back
+			release(content, claimer)
+			return
+		}
+		p.fail(content, claimer, "parse", err)
+		return
+	}
+	if len(work.Pages) == 0 {
+		p.fail(content, claimer, "parse", appErr.NewAppError(appErr.ErrorTypeValidation, "не найдено ни одного изображения", nil))
+		return
+	}
+	// store meta (series, authors, translators, tags, pages); pages are the
+	// baseline for re-parse diffs, so a failed write is a failed attempt
+	if err := database.ContentStoreWork(content.ID, work); err != nil {
+		p.fail(content, claimer, "store", appErr.NewDatabaseError("store work", err).WithTransient(true))
+		return
+	}
+	logger.Info("PROCESSOR", "parsed source=%s url=%s, title=%s, series=%s, authors=%v, translators=%v, tags=%v, pages=%d", parser.Name(), content.SourceURL, work.Title, work.Series, work.Authors, work.Translators, work.Tags, len(work.Pages))
+	imageURLs := work.PageURLs()
+	if p.rehost != nil {
+		imageURLs, err = p.rehostImages(ctx, content, work)
+		if err != nil {
+			if ctx.Err() != nil {
+				release(content, claimer)
+				return
+			}
+			p.fail(content, claimer, "rehost", err)
+			return
+		}
+	}
+	article := p.article(work, imageURLs)
+	urls, accountID, err := p.accounts.create(ctx, func(c *telegraph.Client) ([]string, error) {
+		return c.CreateArticle(ctx, article, p.split)
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			release(content, claimer)
+			return
+		}
+		p.fail(content, claimer, "telegraph", err)
+		return
+	}
+	logger.Info("PROCESSOR", "created telegraph pages account=%d parts=%d url=%s", accountID, len(urls), urls[0])
+	if !settle(content, claimer, database.ContentMarkParsed(content.ID, urls, accountID, claimer)) {
+		return
+	}
+	logger.Info("PROCESSOR", "marked parsed url=%s", content.SourceURL)
+	logger.Info("PROCESSOR", "processed url elapsed=%s", time.Since(start))
+}
+
+// release returns a row interrupted by shutdown or a lost lease to New
+// without counting an attempt.
+func release(content *database.Content, claimer string) {
+	_ = database.ContentReleaseClaim(content.ID, claimer)
+	logger.Info("PROCESSOR", "aborted url=%s", content.SourceURL)
+}
+
+// article оформляет страницу работы по p.layout.
+func (p *processor) article(work *parsers.ParsedWork, imageURLs []string) telegraph.Article {
+	if p.layout == nil {
+		return telegraph.Article{Title: work.Title, Images: imageURLs}
+	}
+	m := *p.layout
+	m.Title, m.AltTitles, m.Series = work.Title, work.AltTitles, work.Series
+	m.Authors, m.Translators, m.Tags = work.Authors, work.Translators, work.Tags
+	m.SourceURL = work.SourceURL
+	return telegraph.BuildArticle(m, imageURLs)
+}
+
+// fail откладывает повтор для временных ошибок и помечает запись Error
+// для постоянных или когда попытки исчерпаны.
+func (p *processor) fail(content *database.Content, claimer, stage string, err error) {
+	attempt := content.Attempts + 1
+	if appErr.IsTransient(err) && attempt < p.maxAttempts {
+		delay := p.backoff(attempt, appErr.RetryAfterOf(err))
+		if settle(content, claimer, database.ContentScheduleRetry(content.ID, err.Error(), time.Now().Add(delay), claimer)) {
+			logger.Warn("PROCESSOR", "%s failed url=%s attempt=%d/%d, retry in %s: %v", stage, content.SourceURL, attempt, p.maxAttempts, delay, err)
+		}
+		return
+	}
+	if settle(content, claimer, database.ContentMarkClaimError(content.ID, err.Error(), claimer)) {
+		logger.Error("PROCESSOR", "%s failed url=%s attempt=%d/%d, giving up: %v", stage, content.SourceURL, attempt, p.maxAttempts, err)
+	}
+}
+
+// settle проверяет итог перехода записи и сообщает, применён ли он.
+// ErrLeaseLost — запись уже вернул reaper или взял другой воркер: результат
+// этого воркера отбрасывается.
+func settle(content *database.Content, claimer string, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, database.ErrLeaseLost):
+		logger.Warn("PROCESSOR", "worker=%s lost lease on id=%d, result dropped", claimer, content.ID)
+	default:
+		logger.DatabaseError("update id=%d: %v", content.ID, err)
+	}
+	return false
+}
+
+// backoff — retryBase * 2^(attempt-1), не больше retryMax и не меньше
+// паузы, которую попросил источник.
+func (p *processor) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	d := p.retryBase
+	for i := 1; i < attempt && d < p.retryMax; i++ {
+		d *= 2
+	}
+	if p.retryMax > 0 && d > p.retryMax {
+		d = p.retryMax
+	}
+	if retryAfter > d {
+		d = retryAfter
+	}
+	return d
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
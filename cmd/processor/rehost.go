@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go_scripts/database"
+	appErr "go_scripts/internal/errors"
+	"go_scripts/internal/logger"
+	"go_scripts/parsers"
+)
+
+// rehostImages перезаливает изображения работы в p.rehost и возвращает их
+// новые URL по порядку страниц (разрезанная полоса даёт несколько URL).
+// Прогресс хранится в content_images, поэтому при повторной попытке
+// загружаются только оставшиеся изображения.
+func (p *processor) rehostImages(ctx context.Context, content *database.Content, work *parsers.ParsedWork) ([]string, error) {
+	imgs, err := database.ContentImagesSync(content.ID, work.Pages)
+	if err != nil {
+		return nil, appErr.NewDatabaseError("sync content images", err).WithTransient(true)
+	}
+	hosted := make([][]string, len(imgs))
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		failed    int
+		firstErr  error
+		transient = true
+	)
+	sem := make(chan struct{}, max(p.imageConcurrency, 1))
+	for i, img := range imgs {
+		if img.HostedURL != "" {
+			hosted[i] = img.HostedURLs()
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, img database.ContentImage) {
+			defer func() { <-sem; wg.Done() }()
+			name := fmt.Sprintf("%d/%03d", content.ID, img.PageIndex)
+			urls, err := p.rehost.Rehost(ctx, img.SourceURL, content.SourceURL, name)
+			if err != nil {
+				_ = database.ContentImageMarkFailed(img.ID, err.Error())
+				logger.Warn("PROCESSOR", "rehost image id=%d page=%d url=%s: %v", content.ID, img.PageIndex, img.SourceURL, err)
+				mu.Lock()
+				failed++
+				if firstErr == nil {
+					firstErr = err
+				}
+				transient = transient && appErr.IsTransient(err)
+				mu.Unlock()
+				return
+			}
+			if err := database.ContentImageMarkHosted(img.ID, urls); err != nil {
+				logger.DatabaseError("mark image hosted id=%d: %v", img.ID, err)
+			}
+			hosted[i] = urls
+		}(i, img)
+	}
+	wg.Wait()
+
+	if failed > 0 {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		msg := fmt.Sprintf("не удалось перезалить %d из %d изображений", failed, len(imgs))
+		if transient {
+			return nil, appErr.NewNetworkError(msg, firstErr).WithRetryAfter(appErr.RetryAfterOf(firstErr))
+		}
+		return nil, appErr.NewNetworkError(msg, firstErr)
+	}
+	var urls []string
+	for _, h := range hosted {
+		urls = append(urls, h...)
+	}
+	logger.Info("PROCESSOR", "rehosted images id=%d pages=%d files=%d store=%s", content.ID, len(imgs), len(urls), p.rehost.Store.Name())
+	return urls, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go_scripts/database"
+	appErr "go_scripts/internal/errors"
+	"go_scripts/internal/logger"
+	"go_scripts/parsers"
+)
+
+// reparse заново разбирает опубликованную работу и, если изображения
+// изменились, редактирует существующие страницы Telegraph по тем же адресам.
+// Статус записи не меняется.
+func (p *processor) reparse(ctx context.Context, content *database.Content, claimer string) {
+	logger.Info("PROCESSOR", "re-parsing id=%d url=%s", content.ID, content.SourceURL)
+	parser, err := parsers.Lookup(content.SourceURL)
+	if err != nil {
+		p.reparseFailed(ctx, content, claimer, err)
+		return
+	}
+	work, err := parser.Parse(ctx, content.SourceURL)
+	if err == nil && len(work.Pages) == 0 {
+		err = appErr.NewAppError(appErr.ErrorTypeValidation, "не найдено ни одного изображения", nil)
+	}
+	if err != nil {
+		p.reparseFailed(ctx, content, claimer, err)
+		return
+	}
+
+	diff := parsers.DiffPages(content.Pages(), work.Pages)
+	if diff.Empty() {
+		if err := database.ContentFinishReparse(content.ID, claimer, work, nil, "pages unchanged"); err != nil {
+			p.reparseFailed(ctx, content, claimer, appErr.NewDatabaseError("store work", err).WithTransient(true))
+			return
+		}
+		logger.Info("PROCESSOR", "re-parse id=%d: pages unchanged", content.ID)
+		return
+	}
+
+	imageURLs := work.PageURLs()
+	if p.rehost != nil {
+		// content_images сохраняет копии неизменившихся страниц, загружаются только новые
+		if imageURLs, err = p.rehostImages(ctx, content, work); err != nil {
+			p.reparseFailed(ctx, content, claimer, err)
+			return
+		}
+	}
+	acc, client, err := p.accounts.owner(content)
+	if err != nil {
+		p.reparseFailed(ctx, content, claimer, err)
+		return
+	}
+	old := content.TelegraphURLs()
+	urls, err := client.EditArticle(ctx, old, p.article(work, imageURLs), p.split)
+	if err != nil {
+		if len(urls) > len(old) {
+			p.accounts.orphaned(acc, urls[len(old):])
+		}
+		p.accounts.flooded(acc, err)
+		p.reparseFailed(ctx, content, claimer, err)
+		return
+	}
+	p.accounts.addPages(acc, len(urls)-len(old))
+	// работа сохраняется только здесь: если правка не удалась, повтор снова
+	// увидит разницу со старыми страницами
+	if err := database.ContentFinishReparse(content.ID, claimer, work, urls, "pages "+diff.String()); err != nil {
+		logger.DatabaseError("finish reparse id=%d: %v", content.ID, err)
+		return
+	}
+	logger.Info("PROCESSOR", "re-parsed id=%d pages %s, parts=%d url=%s", content.ID, diff, len(urls), urls[0])
+}
+
+// reparseFailed откладывает повтор временной ошибки (с той же экспоненциальной
+// паузой, что и обработка) и снимает запрос при постоянной. Прерванный
+// остановкой процессора перепарсинг возвращается в очередь сразу; если
+// аренду забрали, результат отбрасывается.
+func (p *processor) reparseFailed(ctx context.Context, content *database.Content, claimer string, err error) {
+	attempt := content.ReparseAttempts + 1
+	var retryAt *time.Time
+	if ctx.Err() != nil {
+		now := time.Now()
+		retryAt = &now
+		logger.Info("PROCESSOR", "re-parse id=%d aborted, requeued", content.ID)
+	} else if appErr.IsTransient(err) && attempt < p.maxAttempts {
+		at := time.Now().Add(p.backoff(attempt, appErr.RetryAfterOf(err)))
+		retryAt = &at
+		logger.Warn("PROCESSOR", "re-parse id=%d attempt=%d/%d, retry at %s: %v", content.ID, attempt, p.maxAttempts, at.Format(time.RFC3339), err)
+	} else {
+		logger.Error("PROCESSOR", "re-parse id=%d failed, giving up: %v", content.ID, err)
+	}
+	if dbErr := database.ContentReparseFailed(content.ID, claimer, err.Error(), retryAt); errors.Is(dbErr, database.ErrLeaseLost) {
+		logger.Warn("PROCESSOR", "worker=%s lost re-parse lease on id=%d, result dropped", claimer, content.ID)
+	} else if dbErr != nil {
+		logger.DatabaseError("reparse failed id=%d: %v", content.ID, dbErr)
+	}
+}
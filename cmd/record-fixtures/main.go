@@ -0,0 +1,97 @@
+// record-fixtures записывает живые страницы h-chan в parsers/testdata/golden
+// и при необходимости пересобирает golden-файлы.
+//
+//	go run ./cmd/record-fixtures -url https://x5.h-chan.me/manga/123-slug.html -name slug -update-golden
+//	go run ./cmd/record-fixtures -all -update-golden   # перезаписать все кейсы с source.url
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go_scripts/internal/logger"
+	"go_scripts/parsers"
+)
+
+// sourceFile хранит исходную ссылку кейса, чтобы его можно было перезаписать через -all.
+const sourceFile = "source.url"
+
+func main() {
+	url := flag.String("url", "", "ссылка на /manga/ или /online/ страницу")
+	name := flag.String("name", "", "имя кейса (подкаталог в -dir)")
+	dir := flag.String("dir", filepath.Join("parsers", "testdata", "golden"), "каталог с кейсами")
+	all := flag.Bool("all", false, "перезаписать все кейсы, у которых есть "+sourceFile)
+	updateGolden := flag.Bool("update-golden", false, "после записи выполнить go test -update для golden-файлов")
+	flag.Parse()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	switch {
+	case *all:
+		entries, err := os.ReadDir(*dir)
+		if err != nil {
+			logger.Error("FIXTURES", "read dir: %v", err)
+			os.Exit(1)
+		}
+		for _, e := range entries {
+			if !e.IsDir() {
+				continue
+			}
+			b, err := os.ReadFile(filepath.Join(*dir, e.Name(), sourceFile))
+			if err != nil {
+				logger.Warn("FIXTURES", "skip case=%s: no %s", e.Name(), sourceFile)
+				continue
+			}
+			if err := record(ctx, strings.TrimSpace(string(b)), filepath.Join(*dir, e.Name())); err != nil {
+				logger.Error("FIXTURES", "case=%s: %v", e.Name(), err)
+				os.Exit(1)
+			}
+		}
+	case *url != "" && *name != "":
+		if err := record(ctx, *url, filepath.Join(*dir, *name)); err != nil {
+			logger.Error("FIXTURES", "case=%s: %v", *name, err)
+			os.Exit(1)
+		}
+	case !*updateGolden:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *updateGolden {
+		cmd := exec.CommandContext(ctx, "go", "test", "./parsers", "-run", "TestHentaichanGolden", "-update")
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			logger.Error("FIXTURES", "update golden: %v", err)
+			os.Exit(1)
+		}
+		logger.Info("FIXTURES", "golden files updated")
+	}
+}
+
+func record(ctx context.Context, url, caseDir string) error {
+	mangaHTML, onlineHTML, err := parsers.HentaichanFetchPages(ctx, parsers.DefaultFetcher(), url)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(caseDir, 0o755); err != nil {
+		return err
+	}
+	files := map[string]string{
+		"manga.html":  mangaHTML,
+		"online.html": onlineHTML,
+		sourceFile:    url + "\n",
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(caseDir, name), []byte(body), 0o644); err != nil {
+			return err
+		}
+	}
+	logger.Info("FIXTURES", "recorded url=%s into %s", url, caseDir)
+	return nil
+}
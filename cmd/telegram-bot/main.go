@@ -15,6 +15,8 @@ import (
 	"go_scripts/internal/fsm"
 	"go_scripts/internal/logger"
 	"go_scripts/internal/scheduler"
+	"go_scripts/internal/telegram"
+	"go_scripts/parsers"
 )
 
 func main() {
@@ -27,29 +29,86 @@ func main() {
 		os.Exit(1)
 	}
 
-	botURL := c.TelegramAPI + c.TelegramToken
+	tg := telegram.NewClient(telegram.Config{
+		APIURL:  c.TelegramAPI,
+		Token:   c.TelegramToken,
+		Timeout: c.BotTimeout,
+		Limits: &telegram.RateLimits{
+			GlobalPerSec:  c.TelegramGlobalPerSec,
+			ChatInterval:  c.TelegramChatInterval,
+			GroupPerMin:   c.TelegramGroupPerMin,
+			MaxRetries:    c.BotMaxRetries,
+			MaxRetryAfter: c.TelegramMaxRetryAfter,
+		},
+	})
 	if err := database.InitDB(c.DatabaseDSN); err != nil {
 		logger.DatabaseError("init db: %v", err)
 		os.Exit(1)
 	}
 
-	manager := fsm.NewManager(24*time.Hour, 10*time.Minute)
+	// Rule-based parsers are needed here too, so links to those sites pass validation
+	if _, err := parsers.RegisterRulesDir(c.ParserRulesDir); err != nil {
+		logger.BotError("parser rules: %v", err)
+		os.Exit(1)
+	}
+
+	store, err := stateStore(c)
+	if err != nil {
+		logger.DatabaseError("fsm store: %v", err)
+		os.Exit(1)
+	}
+	manager := fsm.NewManager(store, 10*time.Minute)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	// Start scheduler
-	sched := &scheduler.Runner{BotURL: botURL, ChannelID: c.SchedulerTelegramChannelID, IntervalSec: c.SchedulerIntervalSec, SubscribeURL: c.SubscribeLinkURL}
-	go sched.Run(ctx)
+	sched := &scheduler.Runner{Client: tg, ChannelID: c.SchedulerTelegramChannelID, IntervalSec: c.SchedulerIntervalSec, SubscribeURL: c.SubscribeLinkURL}
+	schedDone := make(chan struct{})
+	go func() {
+		defer close(schedDone)
+		sched.Run(ctx)
+	}()
 
 	// Start bot updates loop
-	b := bot.New(botURL, manager)
-	go b.Run(ctx)
+	b := bot.New(tg, manager, bot.Options{Workers: c.BotWorkers, DrainTimeout: c.BotDrainTimeout})
+	if err := b.SyncCommands(ctx); err != nil {
+		logger.TelegramWarn("sync bot commands: %v", err)
+	}
+	// runErr получает итог цикла обновлений: nil после остановки по ctx или
+	// ошибку webhook-сервера
+	runErr := make(chan error, 1)
+	go func() {
+		if c.BotMode == "webhook" {
+			runErr <- b.RunWebhook(ctx, bot.WebhookOptions{URL: c.WebhookURL, Listen: c.WebhookListen, Path: c.WebhookPath, Secret: c.WebhookSecret})
+			return
+		}
+		b.Run(ctx)
+		runErr <- nil
+	}()
 
-	// Graceful shutdown
+	// Graceful shutdown: по сигналу или при падении цикла обновлений
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-	<-sigCh
-	cancel()
+	select {
+	case <-sigCh:
+		cancel()
+		err = <-runErr
+	case err = <-runErr:
+		cancel()
+	}
+	<-schedDone
 	manager.Shutdown()
+	if err != nil {
+		logger.BotError("webhook: %v", err)
+		os.Exit(1)
+	}
+}
+
+// stateStore выбирает хранилище состояний диалогов по FSM_STORE.
+func stateStore(c *config.Config) (fsm.Store, error) {
+	if c.FSMStore == "postgres" {
+		return fsm.NewPostgresStore(database.DB, c.FSMTTL)
+	}
+	return fsm.NewMemoryStore(c.FSMTTL), nil
 }
@@ -0,0 +1,196 @@
+// telegraph-account управляет аккаунтом Telegraph, от имени которого
+// процессор создаёт страницы.
+//
+//	go run ./cmd/telegraph-account create -short-name niko -author-name Niko-San
+//	go run ./cmd/telegraph-account info
+//	go run ./cmd/telegraph-account edit -author-name "New name"
+//	go run ./cmd/telegraph-account revoke
+//	go run ./cmd/telegraph-account pages -limit 20
+//	go run ./cmd/telegraph-account views -path Title-01-01
+//
+// Пул аккаунтов процессора в БД (POSTGRES_DSN):
+//
+//	go run ./cmd/telegraph-account pool-add -short-name niko2 -author-name Niko-San
+//	go run ./cmd/telegraph-account pool-import -token <access_token>
+//	go run ./cmd/telegraph-account pool-list
+//	go run ./cmd/telegraph-account pool-disable -id 2
+//	go run ./cmd/telegraph-account pool-enable -id 2
+//
+// Токен берётся из ACCESS_TOKEN (или -token), адрес API — из TELEGRAPH_API.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"go_scripts/database"
+	"go_scripts/telegraph"
+)
+
+func main() {
+	_ = godotenv.Load()
+	if len(os.Args) < 2 {
+		usage()
+	}
+	cmd := os.Args[1]
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	token := fs.String("token", os.Getenv("ACCESS_TOKEN"), "access token аккаунта")
+	api := fs.String("api", envOr("TELEGRAPH_API", telegraph.DefaultBaseURL), "адрес Telegraph API")
+	shortName := fs.String("short-name", "", "short_name аккаунта")
+	authorName := fs.String("author-name", os.Getenv("AUTHOR_NAME"), "имя автора")
+	authorURL := fs.String("author-url", os.Getenv("AUTHOR_URL"), "ссылка автора")
+	path := fs.String("path", "", "path страницы (для views)")
+	offset := fs.Int("offset", 0, "смещение (для pages)")
+	limit := fs.Int("limit", 50, "количество (для pages)")
+	id := fs.Uint("id", 0, "id аккаунта в пуле (для pool-disable/pool-enable)")
+	_ = fs.Parse(os.Args[2:])
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	c := telegraph.NewClient(telegraph.Config{BaseURL: *api, AccessToken: *token})
+
+	var err error
+	switch cmd {
+	case "create":
+		if *shortName == "" {
+			fail(fmt.Errorf("-short-name обязателен"))
+		}
+		var acc *telegraph.Account
+		if acc, err = c.CreateAccount(ctx, *shortName, *authorName, *authorURL); err == nil {
+			printAccount(acc)
+			fmt.Println("Сохраните access_token в ACCESS_TOKEN")
+		}
+	case "info":
+		var acc *telegraph.Account
+		if acc, err = c.GetAccountInfo(ctx); err == nil {
+			printAccount(acc)
+		}
+	case "edit":
+		var acc *telegraph.Account
+		if acc, err = c.EditAccountInfo(ctx, *shortName, *authorName, *authorURL); err == nil {
+			printAccount(acc)
+		}
+	case "revoke":
+		var acc *telegraph.Account
+		if acc, err = c.RevokeAccessToken(ctx); err == nil {
+			printAccount(acc)
+			fmt.Println("Старый токен больше не действует, обновите ACCESS_TOKEN")
+		}
+	case "pages":
+		var pl *telegraph.PageList
+		if pl, err = c.GetPageList(ctx, *offset, *limit); err == nil {
+			fmt.Printf("total: %d\n", pl.TotalCount)
+			for _, p := range pl.Pages {
+				fmt.Printf("%s\t%d views\t%s\n", p.URL, p.Views, p.Title)
+			}
+		}
+	case "views":
+		if *path == "" {
+			fail(fmt.Errorf("-path обязателен"))
+		}
+		var n int
+		if n, err = c.GetViews(ctx, *path, 0, 0, 0, 0); err == nil {
+			fmt.Printf("%s: %d views\n", *path, n)
+		}
+	case "pool-add":
+		if *shortName == "" {
+			fail(fmt.Errorf("-short-name обязателен"))
+		}
+		initDB()
+		var acc *telegraph.Account
+		if acc, err = c.CreateAccount(ctx, *shortName, *authorName, *authorURL); err == nil {
+			err = addToPool(acc)
+		}
+	case "pool-import":
+		if *token == "" {
+			fail(fmt.Errorf("-token обязателен"))
+		}
+		initDB()
+		var acc *telegraph.Account
+		if acc, err = c.GetAccountInfo(ctx); err == nil {
+			acc.AccessToken = *token
+			err = addToPool(acc)
+		}
+	case "pool-list":
+		initDB()
+		var accs []database.TelegraphAccount
+		if accs, err = database.TelegraphAccountList(); err == nil {
+			for _, a := range accs {
+				state := "enabled"
+				if !a.Enabled {
+					state = "disabled"
+				} else if a.FloodUntil != nil && a.FloodUntil.After(time.Now()) {
+					state = "flood until " + a.FloodUntil.Format(time.RFC3339)
+				}
+				fmt.Printf("%d\t%s\t%s\t%d pages\t%s\n", a.ID, a.ShortName, a.AuthorName, a.PageCount, state)
+			}
+		}
+	case "pool-disable", "pool-enable":
+		if *id == 0 {
+			fail(fmt.Errorf("-id обязателен"))
+		}
+		initDB()
+		err = database.TelegraphAccountSetEnabled(uint(*id), cmd == "pool-enable")
+	default:
+		usage()
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+func initDB() {
+	if err := database.InitDB(os.Getenv("POSTGRES_DSN")); err != nil {
+		fail(err)
+	}
+}
+
+func addToPool(acc *telegraph.Account) error {
+	a := &database.TelegraphAccount{ShortName: acc.ShortName, AuthorName: acc.AuthorName, AuthorURL: acc.AuthorURL, AccessToken: acc.AccessToken}
+	if err := database.TelegraphAccountAdd(a); err != nil {
+		return err
+	}
+	if a.ID == 0 {
+		fmt.Println("Аккаунт уже в пуле")
+		return nil
+	}
+	fmt.Printf("Добавлен в пул: id=%d short_name=%s\n", a.ID, a.ShortName)
+	return nil
+}
+
+func printAccount(a *telegraph.Account) {
+	fmt.Printf("short_name:   %s\n", a.ShortName)
+	fmt.Printf("author_name:  %s\n", a.AuthorName)
+	fmt.Printf("author_url:   %s\n", a.AuthorURL)
+	if a.AccessToken != "" {
+		fmt.Printf("access_token: %s\n", a.AccessToken)
+	}
+	if a.AuthURL != "" {
+		fmt.Printf("auth_url:     %s\n", a.AuthURL)
+	}
+	if a.PageCount > 0 {
+		fmt.Printf("page_count:   %d\n", a.PageCount)
+	}
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: telegraph-account create|info|edit|revoke|pages|views|pool-add|pool-import|pool-list|pool-disable|pool-enable [flags]")
+	os.Exit(2)
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "error:", err)
+	os.Exit(1)
+}
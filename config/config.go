@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	appErr "go_scripts/internal/errors"
@@ -15,11 +16,68 @@ type Config struct {
 	TelegramToken              string
 	DatabaseDSN                string
 	BotTimeout                 time.Duration
-	BotMaxRetries              int
+	BotMaxRetries              int // повторов отправки после 429
+	TelegramGlobalPerSec       int
+	TelegramChatInterval       time.Duration
+	TelegramGroupPerMin        int
+	TelegramMaxRetryAfter      time.Duration
+	BotMode                    string // polling | webhook
+	FSMStore                   string // memory | postgres
+	FSMTTL                     time.Duration
+	BotWorkers                 int
+	BotDrainTimeout            time.Duration
+	WebhookURL                 string
+	WebhookListen              string
+	WebhookPath                string
+	WebhookSecret              string
 	SchedulerIntervalSec       int
 	SchedulerTelegramChannelID int64
 	LoggingLevel               string
 	SubscribeLinkURL           string
+	ParserRulesDir             string
+	HentaichanSelectors        string // файл с селекторами h-chan; пусто — встроенные
+	ParserTimeout              time.Duration
+	ParserConnectTimeout       time.Duration
+	ParserUserAgent            string
+	ParserHeaders              map[string]string
+	ParserProxyURL             string
+	ParserCookieJar            bool
+	ParserRatePerSec           float64
+	ParserBurst                int
+	ParserMaxConcurrentPerHost int
+	ParserMinDelay             time.Duration
+	ParserMaxDelay             time.Duration
+	ParserMaxRetries           int
+	ProcessorMaxAttempts       int
+	ProcessorRetryBase         time.Duration
+	ProcessorRetryMax          time.Duration
+	ProcessorWorkers           int
+	ProcessorDrainTimeout      time.Duration
+	ProcessorLease             time.Duration
+	ProcessorReaperInterval    time.Duration
+	TelegraphAPI               string
+	TelegraphAccessToken       string
+	TelegraphAuthorName        string
+	TelegraphAuthorURL         string
+	TelegraphTimeout           time.Duration
+	TelegraphMaxImagesPerPage  int
+	TelegraphMaxPageBytes      int
+	TelegraphUploadURL         string
+	TelegraphLayout            string // rich | plain
+	TelegraphTagURL            string // шаблон ссылки на тег с {tag}
+	TelegraphSubscribeText     string // текст ссылки подписки в подвале; пусто — "канал"
+	ImageStore                 string
+	ImageStoreDir              string
+	ImageStoreBaseURL          string
+	ImageConcurrency           int
+	ImageMaxAttempts           int
+	ImageRetryBase             time.Duration
+	ImageFormats               []string // пусто — по умолчанию для IMAGE_STORE
+	ImageMaxWidth              int      // 0 — по умолчанию для IMAGE_STORE
+	ImageMaxHeight             int
+	ImageMaxBytes              int
+	ImageJPEGQuality           int
+	ImageKeepMetadata          bool
 }
 
 func Load() (*Config, error) {
@@ -43,6 +101,26 @@ func Load() (*Config, error) {
 	} else {
 		return nil, err
 	}
+	if n, err := parseIntEnv("TELEGRAM_GLOBAL_PER_SEC", "30", "TELEGRAM_GLOBAL_PER_SEC"); err == nil {
+		c.TelegramGlobalPerSec = n
+	} else {
+		return nil, err
+	}
+	if n, err := parseIntEnv("TELEGRAM_CHAT_INTERVAL_MS", "1000", "TELEGRAM_CHAT_INTERVAL_MS"); err == nil {
+		c.TelegramChatInterval = time.Duration(n) * time.Millisecond
+	} else {
+		return nil, err
+	}
+	if n, err := parseIntEnv("TELEGRAM_GROUP_PER_MIN", "20", "TELEGRAM_GROUP_PER_MIN"); err == nil {
+		c.TelegramGroupPerMin = n
+	} else {
+		return nil, err
+	}
+	if n, err := parseIntEnv("TELEGRAM_MAX_RETRY_AFTER_SEC", "300", "TELEGRAM_MAX_RETRY_AFTER_SEC"); err == nil {
+		c.TelegramMaxRetryAfter = time.Duration(n) * time.Second
+	} else {
+		return nil, err
+	}
 
 	if n, err := parseIntEnv("SCHEDULER_INTERVAL_SEC", "10", "SCHEDULER_INTERVAL_SEC"); err == nil {
 		if n <= 0 {
@@ -64,13 +142,247 @@ func Load() (*Config, error) {
 		return nil, appErr.NewValidationError("Отсутствует TELEGRAM_CHANNEL_ID", "Должен быть задан для рассылки")
 	}
 
+	if n, err := parseIntEnv("BOT_WORKERS", "8", "BOT_WORKERS"); err == nil {
+		if n <= 0 {
+			return nil, appErr.NewValidationError("Неверный BOT_WORKERS", "Должен быть числом > 0")
+		}
+		c.BotWorkers = n
+	} else {
+		return nil, err
+	}
+	if n, err := parseIntEnv("BOT_DRAIN_TIMEOUT_SEC", "10", "BOT_DRAIN_TIMEOUT_SEC"); err == nil {
+		c.BotDrainTimeout = time.Duration(n) * time.Second
+	} else {
+		return nil, err
+	}
+	c.FSMStore = getEnv("FSM_STORE", "memory")
+	if c.FSMStore != "memory" && c.FSMStore != "postgres" {
+		return nil, appErr.NewValidationError("Неверный FSM_STORE", "Допустимо memory или postgres")
+	}
+	if n, err := parseIntEnv("FSM_TTL_HOURS", "24", "FSM_TTL_HOURS"); err == nil {
+		if n <= 0 {
+			return nil, appErr.NewValidationError("Неверный FSM_TTL_HOURS", "Должен быть числом > 0")
+		}
+		c.FSMTTL = time.Duration(n) * time.Hour
+	} else {
+		return nil, err
+	}
+	c.BotMode = getEnv("BOT_MODE", "polling")
+	c.WebhookURL = getEnv("WEBHOOK_URL", "")
+	c.WebhookListen = getEnv("WEBHOOK_LISTEN", ":8080")
+	c.WebhookPath = getEnv("WEBHOOK_PATH", "")
+	c.WebhookSecret = getEnv("WEBHOOK_SECRET", "")
+	switch c.BotMode {
+	case "polling":
+	case "webhook":
+		if !strings.HasPrefix(c.WebhookURL, "https://") {
+			return nil, appErr.NewValidationError("Неверный WEBHOOK_URL", "Для BOT_MODE=webhook нужен публичный https:// адрес")
+		}
+		if !validWebhookSecret(c.WebhookSecret) {
+			return nil, appErr.NewValidationError("Неверный WEBHOOK_SECRET", "1-256 символов A-Z, a-z, 0-9, _ и -")
+		}
+	default:
+		return nil, appErr.NewValidationError("Неверный BOT_MODE", "Допустимо polling или webhook")
+	}
+
 	c.LoggingLevel = getEnv("LOG_LEVEL", "INFO")
 	c.SubscribeLinkURL = getEnv("SUBSCRIBE_LINK_URL", "")
+	c.ParserRulesDir = getEnv("PARSER_RULES_DIR", "")
+	c.HentaichanSelectors = getEnv("HENTAICHAN_SELECTORS", "")
+	if t, err := parseIntEnv("PARSER_TIMEOUT_SEC", "15", "PARSER_TIMEOUT_SEC"); err == nil {
+		c.ParserTimeout = time.Duration(t) * time.Second
+	} else {
+		return nil, err
+	}
+	if t, err := parseIntEnv("PARSER_CONNECT_TIMEOUT_SEC", "10", "PARSER_CONNECT_TIMEOUT_SEC"); err == nil {
+		c.ParserConnectTimeout = time.Duration(t) * time.Second
+	} else {
+		return nil, err
+	}
+	c.ParserUserAgent = getEnv("PARSER_USER_AGENT", "")
+	headers, err := parseHeadersEnv("PARSER_HEADERS")
+	if err != nil {
+		return nil, err
+	}
+	c.ParserHeaders = headers
+	c.ParserProxyURL = getEnv("PARSER_PROXY_URL", "")
+	c.ParserCookieJar = getEnv("PARSER_COOKIE_JAR", "false") == "true"
+	rate, err := strconv.ParseFloat(getEnv("PARSER_RATE_PER_SEC", "1"), 64)
+	if err != nil || rate < 0 {
+		return nil, appErr.NewValidationError("Неверный PARSER_RATE_PER_SEC", "Должен быть числом >= 0")
+	}
+	c.ParserRatePerSec = rate
+	if n, err := parseIntEnv("PARSER_BURST", "2", "PARSER_BURST"); err == nil {
+		c.ParserBurst = n
+	} else {
+		return nil, err
+	}
+	if n, err := parseIntEnv("PARSER_MAX_CONCURRENT_PER_HOST", "2", "PARSER_MAX_CONCURRENT_PER_HOST"); err == nil {
+		c.ParserMaxConcurrentPerHost = n
+	} else {
+		return nil, err
+	}
+	if n, err := parseIntEnv("PARSER_DELAY_MIN_MS", "250", "PARSER_DELAY_MIN_MS"); err == nil {
+		c.ParserMinDelay = time.Duration(n) * time.Millisecond
+	} else {
+		return nil, err
+	}
+	if n, err := parseIntEnv("PARSER_DELAY_MAX_MS", "1000", "PARSER_DELAY_MAX_MS"); err == nil {
+		c.ParserMaxDelay = time.Duration(n) * time.Millisecond
+	} else {
+		return nil, err
+	}
+	if n, err := parseIntEnv("PARSER_MAX_RETRIES", "3", "PARSER_MAX_RETRIES"); err == nil {
+		c.ParserMaxRetries = n
+	} else {
+		return nil, err
+	}
+	if n, err := parseIntEnv("PROCESSOR_MAX_ATTEMPTS", "5", "PROCESSOR_MAX_ATTEMPTS"); err == nil {
+		if n <= 0 {
+			return nil, appErr.NewValidationError("Неверный PROCESSOR_MAX_ATTEMPTS", "Должен быть числом > 0")
+		}
+		c.ProcessorMaxAttempts = n
+	} else {
+		return nil, err
+	}
+	if n, err := parseIntEnv("PROCESSOR_RETRY_BASE_SEC", "30", "PROCESSOR_RETRY_BASE_SEC"); err == nil {
+		c.ProcessorRetryBase = time.Duration(n) * time.Second
+	} else {
+		return nil, err
+	}
+	if n, err := parseIntEnv("PROCESSOR_RETRY_MAX_SEC", "3600", "PROCESSOR_RETRY_MAX_SEC"); err == nil {
+		c.ProcessorRetryMax = time.Duration(n) * time.Second
+	} else {
+		return nil, err
+	}
+	if n, err := parseIntEnv("PROCESSOR_WORKERS", "1", "PROCESSOR_WORKERS"); err == nil {
+		if n <= 0 {
+			return nil, appErr.NewValidationError("Неверный PROCESSOR_WORKERS", "Должен быть числом > 0")
+		}
+		c.ProcessorWorkers = n
+	} else {
+		return nil, err
+	}
+	if n, err := parseIntEnv("PROCESSOR_DRAIN_TIMEOUT_SEC", "30", "PROCESSOR_DRAIN_TIMEOUT_SEC"); err == nil {
+		c.ProcessorDrainTimeout = time.Duration(n) * time.Second
+	} else {
+		return nil, err
+	}
+	if n, err := parseIntEnv("PROCESSOR_LEASE_SEC", "120", "PROCESSOR_LEASE_SEC"); err == nil {
+		if n <= 0 {
+			return nil, appErr.NewValidationError("Неверный PROCESSOR_LEASE_SEC", "Должен быть числом > 0")
+		}
+		c.ProcessorLease = time.Duration(n) * time.Second
+	} else {
+		return nil, err
+	}
+	if n, err := parseIntEnv("PROCESSOR_REAPER_INTERVAL_SEC", "30", "PROCESSOR_REAPER_INTERVAL_SEC"); err == nil {
+		if n <= 0 {
+			return nil, appErr.NewValidationError("Неверный PROCESSOR_REAPER_INTERVAL_SEC", "Должен быть числом > 0")
+		}
+		c.ProcessorReaperInterval = time.Duration(n) * time.Second
+	} else {
+		return nil, err
+	}
+	c.TelegraphAPI = getEnv("TELEGRAPH_API", "https://api.telegra.ph")
+	c.TelegraphAccessToken = getEnv("ACCESS_TOKEN", "")
+	c.TelegraphAuthorName = getEnv("AUTHOR_NAME", "")
+	c.TelegraphAuthorURL = getEnv("AUTHOR_URL", "")
+	if t, err := parseIntEnv("TELEGRAPH_TIMEOUT_SEC", "30", "TELEGRAPH_TIMEOUT_SEC"); err == nil {
+		c.TelegraphTimeout = time.Duration(t) * time.Second
+	} else {
+		return nil, err
+	}
+	if n, err := parseIntEnv("TELEGRAPH_MAX_IMAGES_PER_PAGE", "100", "TELEGRAPH_MAX_IMAGES_PER_PAGE"); err == nil {
+		c.TelegraphMaxImagesPerPage = n
+	} else {
+		return nil, err
+	}
+	if n, err := parseIntEnv("TELEGRAPH_MAX_PAGE_BYTES", "60000", "TELEGRAPH_MAX_PAGE_BYTES"); err == nil {
+		c.TelegraphMaxPageBytes = n
+	} else {
+		return nil, err
+	}
+	c.TelegraphUploadURL = getEnv("TELEGRAPH_UPLOAD_URL", "https://telegra.ph/upload")
+	c.TelegraphLayout = getEnv("TELEGRAPH_LAYOUT", "rich")
+	if c.TelegraphLayout != "rich" && c.TelegraphLayout != "plain" {
+		return nil, appErr.NewValidationError("Неверный TELEGRAPH_LAYOUT", "Допустимо rich или plain")
+	}
+	c.TelegraphTagURL = getEnv("TELEGRAPH_TAG_URL", "")
+	c.TelegraphSubscribeText = getEnv("TELEGRAPH_SUBSCRIBE_TEXT", "")
+	c.ImageStore = getEnv("IMAGE_STORE", "telegraph")
+	c.ImageStoreDir = getEnv("IMAGE_STORE_DIR", "")
+	c.ImageStoreBaseURL = getEnv("IMAGE_STORE_BASE_URL", "")
+	if n, err := parseIntEnv("IMAGE_CONCURRENCY", "4", "IMAGE_CONCURRENCY"); err == nil {
+		if n <= 0 {
+			return nil, appErr.NewValidationError("Неверный IMAGE_CONCURRENCY", "Должен быть числом > 0")
+		}
+		c.ImageConcurrency = n
+	} else {
+		return nil, err
+	}
+	if n, err := parseIntEnv("IMAGE_MAX_ATTEMPTS", "3", "IMAGE_MAX_ATTEMPTS"); err == nil {
+		if n <= 0 {
+			return nil, appErr.NewValidationError("Неверный IMAGE_MAX_ATTEMPTS", "Должен быть числом > 0")
+		}
+		c.ImageMaxAttempts = n
+	} else {
+		return nil, err
+	}
+	if n, err := parseIntEnv("IMAGE_RETRY_BASE_SEC", "2", "IMAGE_RETRY_BASE_SEC"); err == nil {
+		c.ImageRetryBase = time.Duration(n) * time.Second
+	} else {
+		return nil, err
+	}
+	for _, f := range strings.Split(getEnv("IMAGE_FORMATS", ""), ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			ct, ok := imageFormats[strings.ToLower(strings.TrimPrefix(f, "image/"))]
+			if !ok {
+				return nil, appErr.NewValidationError("Неверный IMAGE_FORMATS", "Неизвестный формат "+f+", допустимы jpeg, png, gif, webp")
+			}
+			// Telegraph /upload принимает только jpeg, png и gif
+			if ct == "image/webp" && c.ImageStore == "telegraph" {
+				return nil, appErr.NewValidationError("Неверный IMAGE_FORMATS", "webp не поддерживается при IMAGE_STORE=telegraph")
+			}
+			c.ImageFormats = append(c.ImageFormats, ct)
+		}
+	}
+	for _, v := range []struct {
+		key string
+		dst *int
+	}{
+		{"IMAGE_MAX_WIDTH", &c.ImageMaxWidth},
+		{"IMAGE_MAX_HEIGHT", &c.ImageMaxHeight},
+		{"IMAGE_MAX_BYTES", &c.ImageMaxBytes},
+		{"IMAGE_JPEG_QUALITY", &c.ImageJPEGQuality},
+	} {
+		n, err := parseIntEnv(v.key, "0", v.key)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, appErr.NewValidationError("Неверный "+v.key, "Должен быть числом >= 0")
+		}
+		*v.dst = n
+	}
+	if c.ImageJPEGQuality > 100 {
+		return nil, appErr.NewValidationError("Неверный IMAGE_JPEG_QUALITY", "Должен быть от 1 до 100")
+	}
+	c.ImageKeepMetadata = getEnv("IMAGE_KEEP_METADATA", "false") == "true"
 	return c, nil
 }
 
 // Validation helper methods removed; validation occurs in Load() based on SERVICE
 
+// imageFormats — значения IMAGE_FORMATS и соответствующие MIME-типы.
+var imageFormats = map[string]string{
+	"jpeg": "image/jpeg",
+	"jpg":  "image/jpeg",
+	"png":  "image/png",
+	"gif":  "image/gif",
+	"webp": "image/webp",
+}
+
 func getEnv(key, def string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
@@ -78,6 +390,19 @@ func getEnv(key, def string) string {
 	return def
 }
 
+// validWebhookSecret — ограничения Bot API на secret_token.
+func validWebhookSecret(s string) bool {
+	if s == "" || len(s) > 256 {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
 func parseIntEnv(key, def, field string) (int, error) {
 	v := getEnv(key, def)
 	n, err := strconv.Atoi(v)
@@ -95,3 +420,23 @@ func parseInt64Env(key string) (int64, error) {
 	}
 	return n, nil
 }
+
+// parseHeadersEnv разбирает заголовки вида "Name: value|Other: value".
+func parseHeadersEnv(key string) (map[string]string, error) {
+	v := getEnv(key, "")
+	if v == "" {
+		return nil, nil
+	}
+	out := map[string]string{}
+	for _, part := range strings.Split(v, "|") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, appErr.NewValidationError("Неверный "+key, "Ожидается формат Name: value|Other: value")
+		}
+		out[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return out, nil
+}
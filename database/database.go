@@ -15,7 +15,7 @@ func InitDB(dsn string) error {
 	if err != nil {
 		return fmt.Errorf("connect db: %w", err)
 	}
-	if err := DB.AutoMigrate(&Content{}, &Administrator{}); err != nil {
+	if err := migrate(DB); err != nil {
 		return fmt.Errorf("migrate: %w", err)
 	}
 	return nil
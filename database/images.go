@@ -0,0 +1,108 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"go_scripts/parsers"
+)
+
+// ContentImage — изображение страницы работы и его перезалитая копия.
+// Строки переживают повторные попытки обработки, поэтому уже перезалитые
+// изображения не загружаются заново.
+type ContentImage struct {
+	ID        uint   `gorm:"primaryKey"`
+	ContentID uint   `gorm:"not null;uniqueIndex:idx_content_images_page"`
+	PageIndex int    `gorm:"not null;uniqueIndex:idx_content_images_page"`
+	SourceURL string `gorm:"type:text;not null"`
+	HostedURL string `gorm:"type:text"` // пусто — ещё не перезалито
+	// JSON-массив URL частей, если длинная полоса была разрезана
+	HostedPartsJSON string `gorm:"type:text"`
+	Attempts        int    `gorm:"not null;default:0"`
+	LastError       string `gorm:"type:text"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// HostedURLs возвращает URL перезалитых частей изображения по порядку.
+func (img *ContentImage) HostedURLs() []string {
+	if urls := decodeStrings(img.HostedPartsJSON); len(urls) > 0 {
+		return urls
+	}
+	if img.HostedURL != "" {
+		return []string{img.HostedURL}
+	}
+	return nil
+}
+
+// ContentImagesSync приводит изображения записи к списку страниц: новые
+// страницы добавляются, у страниц со сменившимся URL сбрасывается копия,
+// лишние удаляются. Возвращает изображения в порядке страниц.
+func ContentImagesSync(contentID uint, pages []parsers.Page) ([]ContentImage, error) {
+	var out []ContentImage
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var existing []ContentImage
+		if err := tx.Where("content_id = ?", contentID).Find(&existing).Error; err != nil {
+			return err
+		}
+		byIndex := make(map[int]ContentImage, len(existing))
+		for _, img := range existing {
+			byIndex[img.PageIndex] = img
+		}
+		out = make([]ContentImage, 0, len(pages))
+		for _, p := range pages {
+			img, ok := byIndex[p.Index]
+			delete(byIndex, p.Index)
+			switch {
+			case !ok:
+				img = ContentImage{ContentID: contentID, PageIndex: p.Index, SourceURL: p.URL}
+				if err := tx.Create(&img).Error; err != nil {
+					return err
+				}
+			case img.SourceURL != p.URL:
+				img.SourceURL, img.HostedURL, img.HostedPartsJSON, img.Attempts, img.LastError = p.URL, "", "", 0, ""
+				if err := tx.Save(&img).Error; err != nil {
+					return err
+				}
+			}
+			out = append(out, img)
+		}
+		for _, stale := range byIndex {
+			if err := tx.Delete(&ContentImage{}, stale.ID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return out, err
+}
+
+func ContentImageMarkHosted(id uint, hostedURLs []string) error {
+	parts := ""
+	if len(hostedURLs) > 1 {
+		parts = encodeJSON(hostedURLs)
+	}
+	return DB.Model(&ContentImage{}).Where("id = ?", id).Updates(map[string]any{
+		"hosted_url":        hostedURLs[0],
+		"hosted_parts_json": parts,
+		"last_error":        "",
+	}).Error
+}
+
+func ContentImageMarkFailed(id uint, errMsg string) error {
+	return DB.Model(&ContentImage{}).Where("id = ?", id).Updates(map[string]any{
+		"attempts":   gorm.Expr("attempts + 1"),
+		"last_error": errMsg,
+	}).Error
+}
+
+// ContentImagesProgress возвращает число перезалитых и всех изображений записи.
+func ContentImagesProgress(contentID uint) (hosted, total int64, err error) {
+	q := DB.Model(&ContentImage{}).Where("content_id = ?", contentID)
+	if err = q.Count(&total).Error; err != nil {
+		return 0, 0, err
+	}
+	err = DB.Model(&ContentImage{}).Where("content_id = ? AND hosted_url <> ''", contentID).Count(&hosted).Error
+	return hosted, total, err
+}
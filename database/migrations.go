@@ -0,0 +1,72 @@
+package database
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"go_scripts/internal/logger"
+)
+
+// migrate приводит схему к актуальной: сначала переименовывает колонки старой
+// h-chan-схемы, затем выполняет AutoMigrate и переносит данные.
+func migrate(db *gorm.DB) error {
+	legacy, err := renameLegacyContentColumns(db)
+	if err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&Content{}, &ContentEvent{}, &ContentImage{}, &TelegraphAccount{}, &Administrator{}); err != nil {
+		return err
+	}
+	if legacy {
+		if err := db.Model(&Content{}).Where("source IS NULL OR source = ''").Update("source", "hentaichan").Error; err != nil {
+			return fmt.Errorf("backfill source: %w", err)
+		}
+	}
+	return moveLegacyPeopleColumns(db)
+}
+
+// renameLegacyContentColumns переносит url_hentaichan в source_url, сохраняя
+// данные и уникальный индекс. Возвращает true, если схема была старой.
+func renameLegacyContentColumns(db *gorm.DB) (bool, error) {
+	m := db.Migrator()
+	if !m.HasTable(&Content{}) || !m.HasColumn(&Content{}, "url_hentaichan") {
+		return false, nil
+	}
+	logger.DatabaseInfo("migrating contents.url_hentaichan -> source_url")
+	if err := m.RenameColumn(&Content{}, "url_hentaichan", "source_url"); err != nil {
+		return false, fmt.Errorf("rename url_hentaichan: %w", err)
+	}
+	if m.HasIndex(&Content{}, "idx_contents_url_hentaichan") {
+		if err := m.RenameIndex(&Content{}, "idx_contents_url_hentaichan", "idx_contents_source_url"); err != nil {
+			return false, fmt.Errorf("rename url_hentaichan index: %w", err)
+		}
+	}
+	return true, nil
+}
+
+// moveLegacyPeopleColumns переносит строковые author/translator в JSON-массивы
+// authors_json/translators_json и удаляет старые колонки.
+func moveLegacyPeopleColumns(db *gorm.DB) error {
+	m := db.Migrator()
+	moves := []struct{ from, to string }{
+		{"author", "authors_json"},
+		{"translator", "translators_json"},
+	}
+	for _, mv := range moves {
+		if !m.HasColumn(&Content{}, mv.from) {
+			continue
+		}
+		logger.DatabaseInfo("migrating contents.%s -> %s", mv.from, mv.to)
+		q := fmt.Sprintf(`UPDATE contents SET %[2]s = to_json(ARRAY(
+			SELECT trim(x) FROM unnest(string_to_array(%[1]s, ',')) AS x WHERE trim(x) <> ''
+		))::text WHERE coalesce(%[1]s, '') <> '' AND coalesce(%[2]s, '') = ''`, mv.from, mv.to)
+		if err := db.Exec(q).Error; err != nil {
+			return fmt.Errorf("move %s: %w", mv.from, err)
+		}
+		if err := m.DropColumn(&Content{}, mv.from); err != nil {
+			return fmt.Errorf("drop %s: %w", mv.from, err)
+		}
+	}
+	return nil
+}
@@ -1,25 +1,84 @@
 package database
 
 import (
+	"encoding/json"
+	"strings"
 	"time"
+
+	"go_scripts/parsers"
 )
 
 type Content struct {
-	ID            uint `gorm:"primaryKey"`
-	Name          string
-	Series        string
-	Author        string
-	Translator    string
-	TagsJSON      string `gorm:"type:text"`
-	UrlHentaichan string `gorm:"uniqueIndex;not null"`
-	UrlTelegraph  string
-	Status        string     `gorm:"type:varchar(16);index"` // New, Processing, Parsed, Confirmed, Cancelled, Sent, Error
-	LastError     string     `gorm:"type:text"`
-	ScheduledAt   *time.Time `gorm:"index"`
-	SentAt        *time.Time
-	ReviewSentAt  *time.Time `gorm:"index"`
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
+	ID                 uint `gorm:"primaryKey"`
+	Name               string
+	AltTitlesJSON      string `gorm:"type:text"`
+	Series             string
+	AuthorsJSON        string `gorm:"type:text"`
+	TranslatorsJSON    string `gorm:"type:text"`
+	TagsJSON           string `gorm:"type:text"`
+	Language           string `gorm:"type:varchar(8)"`
+	PagesJSON          string `gorm:"type:text"`
+	Source             string `gorm:"type:varchar(32);index"` // имя парсера, см. parsers.SourceParser.Name()
+	SourceID           string
+	SourceURL          string        `gorm:"uniqueIndex;not null"`
+	UrlTelegraph       string        // первая (или единственная) часть
+	TelegraphPartsJSON string        `gorm:"type:text"`              // JSON-массив URL всех частей
+	TelegraphAccountID *uint         `gorm:"index"`                  // аккаунт-владелец страниц, см. TelegraphAccount
+	Status             ContentStatus `gorm:"type:varchar(16);index"` // см. contentTransitions
+	LastError          string        `gorm:"type:text"`
+	Attempts           int           `gorm:"not null;default:0"` // неудачных попыток обработки
+	NextAttemptAt      *time.Time    `gorm:"index"`              // не брать в работу раньше этого времени
+	ClaimedBy          string        `gorm:"type:varchar(128)"`  // воркер, держащий запись в Processing
+	LeaseExpiresAt     *time.Time    `gorm:"index"`              // аренда истекла — запись можно вернуть в New
+	ScheduledAt        *time.Time    `gorm:"index"`
+	SentAt             *time.Time
+	ReviewSentAt       *time.Time `gorm:"index"`
+	ReparseRequestedAt *time.Time `gorm:"index"` // очередь перепарсинга опубликованных записей
+	ReparseAttempts    int        `gorm:"not null;default:0"`
+	ReparseClaimedBy   string     `gorm:"type:varchar(128)"` // воркер, выполняющий перепарсинг; reparse_requested_at — срок его аренды
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+}
+
+func (c *Content) AltTitles() []string   { return decodeStrings(c.AltTitlesJSON) }
+func (c *Content) Authors() []string     { return decodeStrings(c.AuthorsJSON) }
+func (c *Content) Translators() []string { return decodeStrings(c.TranslatorsJSON) }
+func (c *Content) Tags() []string        { return decodeStrings(c.TagsJSON) }
+
+// TelegraphURLs возвращает URL всех частей страницы Telegraph по порядку.
+func (c *Content) TelegraphURLs() []string {
+	if urls := decodeStrings(c.TelegraphPartsJSON); len(urls) > 0 {
+		return urls
+	}
+	if c.UrlTelegraph != "" {
+		return []string{c.UrlTelegraph}
+	}
+	return nil
+}
+
+// Pages возвращает сохранённый список страниц в порядке чтения.
+func (c *Content) Pages() []parsers.Page {
+	var pages []parsers.Page
+	if strings.TrimSpace(c.PagesJSON) != "" {
+		_ = json.Unmarshal([]byte(c.PagesJSON), &pages)
+	}
+	return pages
+}
+
+func decodeStrings(s string) []string {
+	var out []string
+	if strings.TrimSpace(s) != "" {
+		_ = json.Unmarshal([]byte(s), &out)
+	}
+	return out
+}
+
+func encodeJSON(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(b)
 }
 
 type Administrator struct {
@@ -2,20 +2,29 @@ package database
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
+
+	"go_scripts/parsers"
 )
 
-func ContentCreateNew(url string) (*Content, error) {
-	c := &Content{UrlHentaichan: url, Status: "New"}
-	return c, DB.Create(c).Error
+func ContentCreateNew(source, url, actor string) (*Content, error) {
+	c := &Content{Source: source, SourceURL: url, Status: StatusNew}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(c).Error; err != nil {
+			return err
+		}
+		return tx.Create(&ContentEvent{ContentID: c.ID, ToStatus: StatusNew, Actor: actor}).Error
+	})
+	return c, err
 }
 
 func ContentExistsByURL(url string) (bool, error) {
 	var count int64
-	result := DB.Model(&Content{}).Where("url_hentaichan = ?", url).Count(&count)
+	result := DB.Model(&Content{}).Where("source_url = ?", url).Count(&count)
 	if result.Error != nil {
 		return false, result.Error
 	}
@@ -24,7 +33,7 @@ func ContentExistsByURL(url string) (bool, error) {
 
 func ContentGetByURL(url string) (*Content, error) {
 	var content Content
-	result := DB.Where("url_hentaichan = ?", url).First(&content)
+	result := DB.Where("source_url = ?", url).First(&content)
 	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 		return nil, nil
 	}
@@ -40,44 +49,262 @@ func ContentGetByID(id uint) (*Content, error) {
 	return &content, result.Error
 }
 
-func ContentClaimNew() (*Content, error) {
+// ErrLeaseLost — аренда записи истекла и её забрал reaper или другой воркер.
+var ErrLeaseLost = errors.New("content lease lost")
+
+// leaseCleared — поля, которые сбрасываются, когда запись выходит из Processing.
+func leaseCleared(fields map[string]any) map[string]any {
+	fields["claimed_by"] = ""
+	fields["lease_expires_at"] = nil
+	return fields
+}
+
+// ContentClaimNew захватывает одну новую запись и переводит её в Processing,
+// выдавая воркеру claimer аренду на lease. SKIP LOCKED позволяет нескольким
+// воркерам и репликам процессора брать разные строки, не блокируя друг друга.
+func ContentClaimNew(claimer string, lease time.Duration) (*Content, error) {
 	var c Content
-	tx := DB.Begin()
-	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("status = ?", "New").Order("id asc").First(&c).Error; err != nil {
-		tx.Rollback()
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).Where("status = ? AND (next_attempt_at IS NULL OR next_attempt_at <= NOW())", StatusNew).Order("id asc").First(&c).Error; err != nil {
+			return err
+		}
+		expires := time.Now().Add(lease)
+		c.ClaimedBy, c.LeaseExpiresAt = claimer, &expires
+		return applyTransition(tx, &c, StatusProcessing, "processor:"+claimer, "", map[string]any{
+			"claimed_by":       claimer,
+			"lease_expires_at": expires,
+		})
+	})
+	if err != nil {
 		return nil, err
 	}
-	if err := tx.Model(&c).Update("status", "Processing").Error; err != nil {
-		tx.Rollback()
-		return nil, err
+	return &c, nil
+}
+
+// ContentHeartbeat продлевает аренду. ErrLeaseLost означает, что запись
+// больше не принадлежит claimer и обработку нужно прекратить.
+func ContentHeartbeat(id uint, claimer string, lease time.Duration) error {
+	res := DB.Model(&Content{}).Where("id = ? AND status = ? AND claimed_by = ?", id, StatusProcessing, claimer).
+		Update("lease_expires_at", time.Now().Add(lease))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// ContentReapExpiredLeases возвращает в New записи, чья аренда истекла
+// (процессор упал между захватом и завершением), или переводит их в Error,
+// если попыток уже maxAttempts. Записи в Processing без аренды остались
+// от версий до её появления и тоже возвращаются.
+func ContentReapExpiredLeases(maxAttempts int) (int64, error) {
+	var reaped int64
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var rows []Content
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND (lease_expires_at IS NULL OR lease_expires_at < NOW())", StatusProcessing).
+			Find(&rows).Error; err != nil {
+			return err
+		}
+		for i := range rows {
+			c := &rows[i]
+			to := StatusNew
+			if c.Attempts+1 >= maxAttempts {
+				to = StatusError
+			}
+			if err := applyTransition(tx, c, to, "reaper", "lease expired, claimed by "+c.ClaimedBy, leaseCleared(map[string]any{
+				"attempts":   gorm.Expr("attempts + 1"),
+				"last_error": "lease expired",
+			})); err != nil {
+				return err
+			}
+			reaped++
+		}
+		return nil
+	})
+	return reaped, err
+}
+
+// ContentReleaseClaim возвращает захваченную запись в очередь (Processing -> New).
+func ContentReleaseClaim(id uint, claimer string) error {
+	return transition(id, StatusNew, "processor:"+claimer, "released", leaseCleared(map[string]any{}), ownedBy(claimer))
+}
+
+// ownedBy отклоняет переход, если запись уже не принадлежит claimer.
+func ownedBy(claimer string) func(*Content) error {
+	return func(c *Content) error {
+		if c.Status != StatusProcessing || c.ClaimedBy != claimer {
+			return ErrLeaseLost
+		}
+		return nil
+	}
+}
+
+// ContentMarkParsed сохраняет URL частей Telegraph и аккаунт, которым они
+// созданы; в url_telegraph попадает первая часть. ErrLeaseLost — запись
+// уже не принадлежит claimer, результат нужно отбросить.
+func ContentMarkParsed(id uint, telegraphURLs []string, accountID uint, claimer string) error {
+	if len(telegraphURLs) == 0 {
+		return errors.New("no telegraph pages")
 	}
-	if err := tx.Commit().Error; err != nil {
+	return transition(id, StatusParsed, "processor:"+claimer, "", leaseCleared(map[string]any{
+		"url_telegraph":        telegraphURLs[0],
+		"telegraph_parts_json": encodeJSON(telegraphURLs),
+		"telegraph_account_id": accountID,
+		"last_error":           "",
+		"next_attempt_at":      nil,
+	}), ownedBy(claimer))
+}
+
+func ContentStoreWork(id uint, w *parsers.ParsedWork) error {
+	return DB.Model(&Content{}).Where("id = ?", id).Updates(workFields(w)).Error
+}
+
+// workFields — поля записи, которые заполняет разобранная работа.
+func workFields(w *parsers.ParsedWork) map[string]any {
+	return map[string]any{
+		"name":             w.Title,
+		"alt_titles_json":  encodeJSON(w.AltTitles),
+		"series":           w.Series,
+		"authors_json":     encodeJSON(w.Authors),
+		"translators_json": encodeJSON(w.Translators),
+		"tags_json":        encodeJSON(w.Tags),
+		"language":         w.Language,
+		"pages_json":       encodeJSON(w.Pages),
+		"source":           w.Source,
+		"source_id":        w.SourceID,
+	}
+}
+
+// ErrNotPublished — у записи ещё нет страницы Telegraph, перепарсивать нечего.
+var ErrNotPublished = errors.New("content has no telegraph page")
+
+// ContentRequestReparse ставит опубликованную запись в очередь перепарсинга.
+// Статус не меняется: страница обновляется по тем же адресам.
+func ContentRequestReparse(id uint, actor string) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var c Content
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&c, id).Error; err != nil {
+			return err
+		}
+		if c.UrlTelegraph == "" {
+			return fmt.Errorf("%w: content %d (%s)", ErrNotPublished, id, c.Status)
+		}
+		if err := tx.Model(&Content{}).Where("id = ?", id).Updates(map[string]any{
+			"reparse_requested_at": time.Now(),
+			"reparse_attempts":     0,
+			"reparse_claimed_by":   "", // идущий перепарсинг теряет аренду и уступает новому
+		}).Error; err != nil {
+			return err
+		}
+		return recordEvent(tx, &c, actor, "reparse requested")
+	})
+}
+
+// ContentClaimReparse забирает одну запись из очереди перепарсинга в аренду
+// claimer на lease. Запрос не снимается, а откладывается до конца аренды:
+// если процессор упадёт, запись снова станет доступна. Аренду продлевает
+// ContentReparseHeartbeat, снимают ContentFinishReparse и ContentReparseFailed.
+func ContentClaimReparse(claimer string, lease time.Duration) (*Content, error) {
+	var c Content
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).Where("reparse_requested_at <= NOW()").Order("reparse_requested_at asc").First(&c).Error; err != nil {
+			return err
+		}
+		until := time.Now().Add(lease)
+		c.ReparseRequestedAt, c.ReparseClaimedBy = &until, claimer
+		return tx.Model(&Content{}).Where("id = ?", c.ID).Updates(map[string]any{
+			"reparse_requested_at": until,
+			"reparse_claimed_by":   claimer,
+		}).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 	return &c, nil
 }
 
-func ContentMarkParsed(id uint, telegraphURL string) error {
-	return DB.Model(&Content{}).Where("id = ?", id).Updates(map[string]any{
-		"url_telegraph": telegraphURL,
-		"status":        "Parsed",
-		"last_error":    "",
-	}).Error
+// ContentReparseHeartbeat продлевает аренду перепарсинга. ErrLeaseLost
+// означает, что запись забрал другой воркер или перепарсинг запрошен заново.
+func ContentReparseHeartbeat(id uint, claimer string, lease time.Duration) error {
+	res := DB.Model(&Content{}).Where("id = ? AND reparse_claimed_by = ? AND reparse_requested_at IS NOT NULL", id, claimer).
+		Update("reparse_requested_at", time.Now().Add(lease))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrLeaseLost
+	}
+	return nil
 }
 
-func ContentUpdateMeta(id uint, name, series, author, translator, tagsJSON string) error {
-	return DB.Model(&Content{}).Where("id = ?", id).Updates(map[string]any{
-		"name":       name,
-		"series":     series,
-		"author":     author,
-		"translator": translator,
-		"tags_json":  tagsJSON,
-	}).Error
+// reparseOwnedBy проверяет, что перепарсинг записи всё ещё арендован claimer.
+func reparseOwnedBy(c *Content, claimer string) error {
+	if c.ReparseRequestedAt == nil || c.ReparseClaimedBy != claimer {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// ContentFinishReparse сохраняет разобранную работу и URL частей после
+// перепарсинга одной транзакцией. Вызывается только после успешного
+// редактирования Telegraph: иначе повтор сравнил бы страницы сами с собой
+// и не обновил бы статью. note описывает изменения для истории.
+func ContentFinishReparse(id uint, claimer string, w *parsers.ParsedWork, telegraphURLs []string, note string) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var c Content
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&c, id).Error; err != nil {
+			return err
+		}
+		if err := reparseOwnedBy(&c, claimer); err != nil {
+			return err
+		}
+		fields := workFields(w)
+		fields["reparse_attempts"] = 0
+		fields["reparse_requested_at"] = nil
+		fields["reparse_claimed_by"] = ""
+		if len(telegraphURLs) > 0 {
+			fields["url_telegraph"] = telegraphURLs[0]
+			fields["telegraph_parts_json"] = encodeJSON(telegraphURLs)
+		}
+		if err := tx.Model(&Content{}).Where("id = ?", id).Updates(fields).Error; err != nil {
+			return err
+		}
+		return recordEvent(tx, &c, "processor:"+claimer, "reparsed: "+note)
+	})
+}
+
+// ContentReparseFailed записывает неудачу перепарсинга, снимает аренду и,
+// если retryAt задан, возвращает запрос в очередь на это время.
+func ContentReparseFailed(id uint, claimer, errMsg string, retryAt *time.Time) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var c Content
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&c, id).Error; err != nil {
+			return err
+		}
+		if err := reparseOwnedBy(&c, claimer); err != nil {
+			return err
+		}
+		fields := map[string]any{
+			"reparse_attempts":     gorm.Expr("reparse_attempts + 1"),
+			"reparse_requested_at": retryAt,
+			"reparse_claimed_by":   "",
+		}
+		if retryAt == nil {
+			fields["reparse_attempts"] = 0
+		}
+		if err := tx.Model(&Content{}).Where("id = ?", id).Updates(fields).Error; err != nil {
+			return err
+		}
+		return recordEvent(tx, &c, "processor:"+claimer, "reparse failed: "+errMsg)
+	})
 }
 
 func ContentFindDue(limit int) ([]Content, error) {
 	var rows []Content
-	q := DB.Where("status = ? AND scheduled_at <= NOW()", "Confirmed").Order("scheduled_at asc")
+	q := DB.Where("status = ? AND scheduled_at <= NOW()", StatusConfirmed).Order("scheduled_at asc")
 	if limit > 0 {
 		q = q.Limit(limit)
 	}
@@ -89,7 +316,7 @@ func ContentFindDue(limit int) ([]Content, error) {
 
 func ContentLastScheduledAt() (*time.Time, error) {
 	var row Content
-	res := DB.Where("status = ? AND scheduled_at IS NOT NULL", "Confirmed").Order("scheduled_at desc").Limit(1).First(&row)
+	res := DB.Where("status = ? AND scheduled_at IS NOT NULL", StatusConfirmed).Order("scheduled_at desc").Limit(1).First(&row)
 	if errors.Is(res.Error, gorm.ErrRecordNotFound) {
 		return nil, nil
 	}
@@ -99,24 +326,44 @@ func ContentLastScheduledAt() (*time.Time, error) {
 	return row.ScheduledAt, nil
 }
 
-func ContentMarkSent(id uint) error {
+func ContentMarkSent(id uint, actor string) error {
 	now := time.Now()
-	return DB.Model(&Content{}).Where("id = ?", id).Updates(map[string]any{
-		"status":  "Sent",
+	return transition(id, StatusSent, actor, "", map[string]any{
 		"sent_at": &now,
-	}).Error
+	}, nil)
 }
 
-func ContentMarkError(id uint, errMsg string) error {
-	return DB.Model(&Content{}).Where("id = ?", id).Updates(map[string]any{
-		"status":     "Error",
+func ContentMarkError(id uint, errMsg, actor string) error {
+	return markError(id, errMsg, actor, nil)
+}
+
+// ContentMarkClaimError — ContentMarkError для записи, которую обрабатывает
+// claimer; ErrLeaseLost — запись уже не его.
+func ContentMarkClaimError(id uint, errMsg, claimer string) error {
+	return markError(id, errMsg, "processor:"+claimer, ownedBy(claimer))
+}
+
+func markError(id uint, errMsg, actor string, check func(*Content) error) error {
+	return transition(id, StatusError, actor, errMsg, leaseCleared(map[string]any{
 		"last_error": errMsg,
-	}).Error
+		"attempts":   gorm.Expr("attempts + 1"),
+	}), check)
+}
+
+// ContentScheduleRetry возвращает запись claimer в New после временной
+// ошибки; ContentClaimNew не возьмёт её раньше at. ErrLeaseLost — запись
+// уже не принадлежит claimer.
+func ContentScheduleRetry(id uint, errMsg string, at time.Time, claimer string) error {
+	return transition(id, StatusNew, "processor:"+claimer, "retry: "+errMsg, leaseCleared(map[string]any{
+		"last_error":      errMsg,
+		"attempts":        gorm.Expr("attempts + 1"),
+		"next_attempt_at": at,
+	}), ownedBy(claimer))
 }
 
 func ContentFindParsedPendingReview(limit int) ([]Content, error) {
 	var rows []Content
-	q := DB.Where("status = ? AND review_sent_at IS NULL", "Parsed").Order("id asc")
+	q := DB.Where("status = ? AND review_sent_at IS NULL", StatusParsed).Order("id asc")
 	if limit > 0 {
 		q = q.Limit(limit)
 	}
@@ -133,24 +380,19 @@ func ContentMarkReviewSent(id uint) error {
 	}).Error
 }
 
-func ContentMarkConfirmed(id uint) error {
-	return DB.Model(&Content{}).Where("id = ?", id).Updates(map[string]any{
-		"status": "Confirmed",
-	}).Error
+func ContentMarkConfirmed(id uint, actor string) error {
+	return transition(id, StatusConfirmed, actor, "", nil, nil)
 }
 
-func ContentMarkCancelled(id uint) error {
-	return DB.Model(&Content{}).Where("id = ?", id).Updates(map[string]any{
-		"status": "Cancelled",
-	}).Error
+func ContentMarkCancelled(id uint, actor string) error {
+	return transition(id, StatusCancelled, actor, "", nil, nil)
 }
 
-func ContentMarkConfirmedAndSchedule(id uint, scheduleAt time.Time) error {
-	return DB.Model(&Content{}).Where("id = ?", id).Updates(map[string]any{
-		"status":       "Confirmed",
+func ContentMarkConfirmedAndSchedule(id uint, scheduleAt time.Time, actor string) error {
+	return transition(id, StatusConfirmed, actor, "", map[string]any{
 		"scheduled_at": scheduleAt,
 		"last_error":   "",
-	}).Error
+	}, nil)
 }
 
 // Administrators
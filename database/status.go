@@ -0,0 +1,107 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ContentStatus — статус записи Content. Меняется только через transition.
+type ContentStatus string
+
+const (
+	StatusNew        ContentStatus = "New"
+	StatusProcessing ContentStatus = "Processing"
+	StatusParsed     ContentStatus = "Parsed"
+	StatusConfirmed  ContentStatus = "Confirmed"
+	StatusCancelled  ContentStatus = "Cancelled"
+	StatusSent       ContentStatus = "Sent"
+	StatusError      ContentStatus = "Error"
+)
+
+// contentTransitions — единственная таблица допустимых переходов статуса.
+var contentTransitions = map[ContentStatus][]ContentStatus{
+	StatusNew:        {StatusProcessing, StatusCancelled},
+	StatusProcessing: {StatusNew, StatusParsed, StatusError},
+	StatusParsed:     {StatusConfirmed, StatusCancelled, StatusError},
+	StatusConfirmed:  {StatusSent, StatusCancelled, StatusError},
+	StatusError:      {StatusNew},
+	StatusCancelled:  {},
+	StatusSent:       {},
+}
+
+// CanTransitionTo сообщает, разрешён ли переход s -> to.
+func (s ContentStatus) CanTransitionTo(to ContentStatus) bool {
+	for _, t := range contentTransitions[s] {
+		if t == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrInvalidTransition — запрошенный переход статуса запрещён таблицей переходов.
+var ErrInvalidTransition = errors.New("invalid content status transition")
+
+// ContentEvent — запись истории смены статуса.
+type ContentEvent struct {
+	ID         uint          `gorm:"primaryKey"`
+	ContentID  uint          `gorm:"index;not null"`
+	FromStatus ContentStatus `gorm:"type:varchar(16)"`
+	ToStatus   ContentStatus `gorm:"type:varchar(16);not null"`
+	Actor      string        `gorm:"type:varchar(128)"` // processor:<worker>, reaper, scheduler, admin:<tg id>, ...
+	Note       string        `gorm:"type:text"`
+	CreatedAt  time.Time     `gorm:"index"`
+}
+
+// applyTransition проверяет переход для уже заблокированной в tx записи c,
+// обновляет её полями fields и пишет событие в content_events.
+func applyTransition(tx *gorm.DB, c *Content, to ContentStatus, actor, note string, fields map[string]any) error {
+	from := c.Status
+	if !from.CanTransitionTo(to) {
+		return fmt.Errorf("%w: content %d %s -> %s", ErrInvalidTransition, c.ID, from, to)
+	}
+	if fields == nil {
+		fields = map[string]any{}
+	}
+	fields["status"] = to
+	if err := tx.Model(&Content{}).Where("id = ?", c.ID).Updates(fields).Error; err != nil {
+		return err
+	}
+	c.Status = to
+	return tx.Create(&ContentEvent{ContentID: c.ID, FromStatus: from, ToStatus: to, Actor: actor, Note: note}).Error
+}
+
+// recordEvent пишет в историю событие без смены статуса (перепарсинг и т.п.).
+func recordEvent(tx *gorm.DB, c *Content, actor, note string) error {
+	return tx.Create(&ContentEvent{ContentID: c.ID, FromStatus: c.Status, ToStatus: c.Status, Actor: actor, Note: note}).Error
+}
+
+// transition блокирует запись id и переводит её в to. check, если задан,
+// может отклонить переход по состоянию записи (например, чужая аренда).
+func transition(id uint, to ContentStatus, actor, note string, fields map[string]any, check func(*Content) error) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var c Content
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&c, id).Error; err != nil {
+			return err
+		}
+		if check != nil {
+			if err := check(&c); err != nil {
+				return err
+			}
+		}
+		return applyTransition(tx, &c, to, actor, note, fields)
+	})
+}
+
+// ContentHistory возвращает историю переходов записи в хронологическом порядке.
+func ContentHistory(id uint) ([]ContentEvent, error) {
+	var rows []ContentEvent
+	if err := DB.Where("content_id = ?", id).Order("id asc").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
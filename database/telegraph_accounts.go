@@ -0,0 +1,127 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TelegraphAccount — аккаунт Telegraph из пула, от имени которого процессор
+// создаёт страницы. Редактировать страницу может только создавший её аккаунт,
+// поэтому Content.TelegraphAccountID запоминает владельца.
+type TelegraphAccount struct {
+	ID          uint       `gorm:"primaryKey"`
+	ShortName   string     `gorm:"type:varchar(32)"`
+	AuthorName  string     `gorm:"type:varchar(128)"`
+	AuthorURL   string     `gorm:"type:varchar(512)"`
+	AccessToken string     `gorm:"type:varchar(128);uniqueIndex;not null"`
+	Enabled     bool       `gorm:"not null;default:true"`
+	PageCount   int        `gorm:"not null;default:0"` // страниц, созданных процессором
+	FloodUntil  *time.Time // FLOOD_WAIT: не использовать до этого времени
+	LastUsedAt  *time.Time `gorm:"index"`
+	LastError   string     `gorm:"type:text"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// ErrNoTelegraphAccount — в пуле нет ни одного включённого аккаунта.
+var ErrNoTelegraphAccount = errors.New("no enabled telegraph accounts")
+
+// TelegraphAccountAdd добавляет аккаунт в пул; существующий токен не дублируется.
+func TelegraphAccountAdd(a *TelegraphAccount) error {
+	a.Enabled = true
+	return DB.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "access_token"}}, DoNothing: true}).Create(a).Error
+}
+
+func TelegraphAccountList() ([]TelegraphAccount, error) {
+	var rows []TelegraphAccount
+	if err := DB.Order("id asc").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func TelegraphAccountGet(id uint) (*TelegraphAccount, error) {
+	var a TelegraphAccount
+	if err := DB.First(&a, id).Error; err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func TelegraphAccountGetByToken(token string) (*TelegraphAccount, error) {
+	var a TelegraphAccount
+	if err := DB.Where("access_token = ?", token).First(&a).Error; err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// TelegraphAccountNext выбирает по кругу включённый аккаунт без активного
+// FLOOD_WAIT — тот, что дольше всех не использовался. Если все аккаунты ждут,
+// возвращает gorm.ErrRecordNotFound и время, когда освободится ближайший.
+func TelegraphAccountNext() (*TelegraphAccount, *time.Time, error) {
+	a, err := telegraphAccountPick("SKIP LOCKED")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Свободные аккаунты могли быть лишь заняты выбором в другом воркере:
+		// ждём его короткую транзакцию, а не считаем весь пул во FLOOD_WAIT.
+		a, err = telegraphAccountPick("")
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		var soonest TelegraphAccount
+		if e := DB.Where("enabled").Order("flood_until asc").First(&soonest).Error; errors.Is(e, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrNoTelegraphAccount
+		}
+		return nil, soonest.FloodUntil, err
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return a, nil, nil
+}
+
+// telegraphAccountPick блокирует подходящий аккаунт с опцией блокировки
+// lockOptions и отмечает его использование.
+func telegraphAccountPick(lockOptions string) (*TelegraphAccount, error) {
+	var a TelegraphAccount
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: lockOptions}).
+			Where("enabled AND (flood_until IS NULL OR flood_until <= NOW())").
+			Order("last_used_at asc nulls first, id asc").First(&a).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		a.LastUsedAt = &now
+		return tx.Model(&TelegraphAccount{}).Where("id = ?", a.ID).Update("last_used_at", now).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// TelegraphAccountFlood откладывает использование аккаунта до until.
+func TelegraphAccountFlood(id uint, until time.Time, errMsg string) error {
+	return DB.Model(&TelegraphAccount{}).Where("id = ?", id).Updates(map[string]any{
+		"flood_until": until,
+		"last_error":  errMsg,
+	}).Error
+}
+
+// TelegraphAccountAddPages увеличивает счётчик созданных аккаунтом страниц.
+func TelegraphAccountAddPages(id uint, n int) error {
+	if n <= 0 {
+		return nil
+	}
+	return DB.Model(&TelegraphAccount{}).Where("id = ?", id).Update("page_count", gorm.Expr("page_count + ?", n)).Error
+}
+
+func TelegraphAccountSetEnabled(id uint, enabled bool) error {
+	res := DB.Model(&TelegraphAccount{}).Where("id = ?", id).Update("enabled", enabled)
+	if res.Error == nil && res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return res.Error
+}
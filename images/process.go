@@ -0,0 +1,174 @@
+package images
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"slices"
+
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+
+	appErr "go_scripts/internal/errors"
+	"go_scripts/telegraph"
+)
+
+// Target — требования хранилища к изображениям. Нулевые ограничения не применяются.
+type Target struct {
+	Formats       []string // допустимые MIME-типы; пусто — любые
+	MaxWidth      int      // шире — уменьшается пропорционально
+	MaxHeight     int      // выше — режется на полосы (вебтуны)
+	MaxBytes      int      // больше — пережимается, при необходимости уменьшается
+	JPEGQuality   int      // 0 — 85
+	StripMetadata bool     // всегда перекодировать, чтобы убрать EXIF и текстовые чанки
+}
+
+// DefaultTarget возвращает требования по умолчанию для хранилища store.
+func DefaultTarget(store string) Target {
+	switch store {
+	case "telegraph":
+		return Target{
+			Formats:       []string{"image/jpeg", "image/png", "image/gif"},
+			MaxWidth:      2560,
+			MaxHeight:     5000,
+			MaxBytes:      telegraph.MaxUploadSize,
+			JPEGQuality:   85,
+			StripMetadata: true,
+		}
+	}
+	return Target{JPEGQuality: 85, StripMetadata: true}
+}
+
+func (t Target) accepts(ct string) bool {
+	return len(t.Formats) == 0 || slices.Contains(t.Formats, ct)
+}
+
+// Image — закодированное изображение, готовое к сохранению.
+type Image struct {
+	Data        []byte
+	ContentType string
+}
+
+// Process приводит изображение к требованиям t: конвертирует неподдерживаемые
+// форматы (webp), уменьшает слишком широкие, режет слишком длинные на части
+// и укладывает в MaxBytes. Подходящий файл возвращается как есть.
+func Process(data []byte, t Target) ([]Image, error) {
+	ct := DetectType(data)
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, notImage(ct, err)
+	}
+	fits := t.accepts(ct) &&
+		(t.MaxWidth <= 0 || cfg.Width <= t.MaxWidth) &&
+		(t.MaxHeight <= 0 || cfg.Height <= t.MaxHeight) &&
+		(t.MaxBytes <= 0 || len(data) <= t.MaxBytes)
+	// GIF не перекодируем ради метаданных: при декодировании теряется анимация
+	if fits && (!t.StripMetadata || ct == "image/gif") {
+		return []Image{{Data: data, ContentType: ct}}, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, notImage(ct, err)
+	}
+	if b := img.Bounds(); t.MaxWidth > 0 && b.Dx() > t.MaxWidth {
+		img = scale(img, t.MaxWidth, b.Dy()*t.MaxWidth/b.Dx())
+	}
+	var out []Image
+	for _, part := range splitStrip(img, t.MaxHeight) {
+		enc, err := encode(part, outputType(ct, part, t), t)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, enc)
+	}
+	return out, nil
+}
+
+func notImage(ct string, err error) error {
+	return appErr.NewAppError(appErr.ErrorTypeValidation, "не удалось декодировать изображение ("+ct+")", err)
+}
+
+// outputType выбирает формат перекодированного изображения: исходный, если
+// хранилище его принимает и есть кодировщик, иначе PNG для прозрачных и JPEG.
+func outputType(src string, img image.Image, t Target) string {
+	if (src == "image/jpeg" || src == "image/png") && t.accepts(src) {
+		return src
+	}
+	if !opaque(img) && t.accepts("image/png") {
+		return "image/png"
+	}
+	if t.accepts("image/jpeg") {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
+
+// encode кодирует img, а если результат больше MaxBytes — переходит с PNG
+// на JPEG, снижает качество и в крайнем случае уменьшает изображение.
+func encode(img image.Image, ct string, t Target) (Image, error) {
+	q := t.JPEGQuality
+	if q <= 0 {
+		q = 85
+	}
+	for i := 0; i < 10; i++ {
+		var buf bytes.Buffer
+		var err error
+		if ct == "image/png" {
+			err = png.Encode(&buf, img)
+		} else {
+			err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: q})
+		}
+		if err != nil {
+			return Image{}, appErr.NewInternalError("encode image", err)
+		}
+		if t.MaxBytes <= 0 || buf.Len() <= t.MaxBytes {
+			return Image{Data: buf.Bytes(), ContentType: ct}, nil
+		}
+		switch {
+		case ct == "image/png" && t.accepts("image/jpeg") && opaque(img):
+			ct = "image/jpeg"
+		case ct == "image/jpeg" && q > 60:
+			q -= 10
+		default:
+			b := img.Bounds()
+			img = scale(img, b.Dx()*4/5, b.Dy()*4/5)
+		}
+	}
+	return Image{}, appErr.NewAppError(appErr.ErrorTypeValidation, fmt.Sprintf("не удалось уложить изображение в %d байт", t.MaxBytes), nil)
+}
+
+// splitStrip режет изображение выше maxHeight на полосы примерно равной высоты.
+func splitStrip(img image.Image, maxHeight int) []image.Image {
+	b := img.Bounds()
+	if maxHeight <= 0 || b.Dy() <= maxHeight {
+		return []image.Image{img}
+	}
+	n := (b.Dy() + maxHeight - 1) / maxHeight
+	step := (b.Dy() + n - 1) / n
+	parts := make([]image.Image, 0, n)
+	for y := b.Min.Y; y < b.Max.Y; y += step {
+		r := image.Rect(b.Min.X, y, b.Max.X, min(y+step, b.Max.Y))
+		dst := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+		draw.Draw(dst, dst.Bounds(), img, r.Min, draw.Src)
+		parts = append(parts, dst)
+	}
+	return parts
+}
+
+func scale(img image.Image, w, h int) image.Image {
+	dst := image.NewRGBA(image.Rect(0, 0, max(w, 1), max(h, 1)))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), xdraw.Src, nil)
+	return dst
+}
+
+func opaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
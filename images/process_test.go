@@ -0,0 +1,100 @@
+package images
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"testing"
+)
+
+func encodeJPEG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 7), uint8(y * 3), uint8(x ^ y), 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func decodeSize(t *testing.T, img Image) (int, int) {
+	t.Helper()
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(img.Data))
+	if err != nil {
+		t.Fatalf("decode %s: %v", img.ContentType, err)
+	}
+	return cfg.Width, cfg.Height
+}
+
+func TestProcessConvertsWebP(t *testing.T) {
+	data, err := os.ReadFile("testdata/sample.webp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := Process(data, DefaultTarget("telegraph"))
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if len(out) != 1 || out[0].ContentType != "image/jpeg" {
+		t.Fatalf("out = %d images, type %q", len(out), out[0].ContentType)
+	}
+	if DetectType(out[0].Data) != "image/jpeg" {
+		t.Errorf("data is %s", DetectType(out[0].Data))
+	}
+
+	// хранилище без ограничений принимает webp как есть
+	out, err = Process(data, Target{})
+	if err != nil || out[0].ContentType != "image/webp" || !bytes.Equal(out[0].Data, data) {
+		t.Errorf("passthrough: %v %q", err, out[0].ContentType)
+	}
+}
+
+func TestProcessResizesAndSplits(t *testing.T) {
+	data := encodeJPEG(t, 200, 1000)
+	out, err := Process(data, Target{MaxWidth: 100, MaxHeight: 200})
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	// 200x1000 -> 100x500 -> 3 полосы по ~167 px
+	if len(out) != 3 {
+		t.Fatalf("parts = %d", len(out))
+	}
+	total := 0
+	for _, img := range out {
+		w, h := decodeSize(t, img)
+		if w != 100 || h > 200 || img.ContentType != "image/jpeg" {
+			t.Errorf("part %dx%d %s", w, h, img.ContentType)
+		}
+		total += h
+	}
+	if total != 500 {
+		t.Errorf("total height = %d", total)
+	}
+}
+
+func TestProcessFitsMaxBytes(t *testing.T) {
+	data := encodeJPEG(t, 400, 400)
+	limit := len(data) / 3
+	out, err := Process(data, Target{MaxBytes: limit})
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if len(out[0].Data) > limit {
+		t.Errorf("size %d > %d", len(out[0].Data), limit)
+	}
+}
+
+func TestProcessKeepsFittingImage(t *testing.T) {
+	data := encodeJPEG(t, 50, 50)
+	out, err := Process(data, Target{MaxWidth: 100, MaxBytes: 1 << 20})
+	if err != nil || len(out) != 1 || !bytes.Equal(out[0].Data, data) {
+		t.Errorf("image was re-encoded: %v", err)
+	}
+}
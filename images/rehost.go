@@ -0,0 +1,108 @@
+package images
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	appErr "go_scripts/internal/errors"
+	"go_scripts/parsers"
+)
+
+// Rehoster скачивает изображение с источника, приводит его к требованиям
+// Target и сохраняет в Store, повторяя попытку при временных ошибках.
+type Rehoster struct {
+	Fetcher     *parsers.Fetcher // nil — parsers.DefaultFetcher()
+	Store       Store
+	Target      Target
+	MaxAttempts int           // попыток на одно изображение, минимум 1
+	RetryBase   time.Duration // пауза перед второй попыткой, дальше удваивается
+}
+
+// Rehost загружает src (с Referer страницы работы) и сохраняет под именем
+// name + расширение по фактическому типу файла. Возвращает новые URL: больше
+// одного, если длинная полоса была разрезана на части.
+func (r *Rehoster) Rehost(ctx context.Context, src, referer, name string) ([]string, error) {
+	attempts := r.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := r.RetryBase
+	var err error
+	for attempt := 1; ; attempt++ {
+		var urls []string
+		urls, err = r.once(ctx, src, referer, name)
+		if err == nil {
+			return urls, nil
+		}
+		if attempt >= attempts || !appErr.IsTransient(err) || ctx.Err() != nil {
+			return nil, err
+		}
+		wait := delay
+		if ra := appErr.RetryAfterOf(err); ra > wait {
+			wait = ra
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		delay *= 2
+	}
+}
+
+func (r *Rehoster) once(ctx context.Context, src, referer, name string) ([]string, error) {
+	f := r.Fetcher
+	if f == nil {
+		f = parsers.DefaultFetcher()
+	}
+	data, err := f.GetBytesWithReferer(ctx, src, referer)
+	if err != nil {
+		return nil, err
+	}
+	if ct := DetectType(data); !strings.HasPrefix(ct, "image/") {
+		// CDN вместо картинки отдал страницу-заглушку или ошибку
+		return nil, appErr.NewNetworkError("не изображение ("+ct+"): "+src, nil)
+	}
+	parts, err := Process(data, r.Target)
+	if err != nil {
+		return nil, err
+	}
+	urls := make([]string, 0, len(parts))
+	for i, p := range parts {
+		n := name
+		if len(parts) > 1 {
+			n = fmt.Sprintf("%s-%d", name, i+1)
+		}
+		u, err := r.Store.Put(ctx, n+Ext(p.ContentType), p.ContentType, p.Data)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, u)
+	}
+	return urls, nil
+}
+
+// DetectType определяет MIME-тип по содержимому файла.
+func DetectType(data []byte) string {
+	ct, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	return ct
+}
+
+// Ext возвращает расширение файла для MIME-типа изображения.
+func Ext(contentType string) string {
+	switch contentType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	}
+	return ""
+}
@@ -0,0 +1,85 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go_scripts/parsers"
+)
+
+func testFetcher(t *testing.T) *parsers.Fetcher {
+	t.Helper()
+	o := parsers.DefaultHTTPOptions()
+	o.Politeness = parsers.PolitenessOptions{}
+	f, err := parsers.NewFetcher(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func pngBytes(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestRehostRetriesAndStores(t *testing.T) {
+	img := pngBytes(t)
+	calls := 0
+	var referer string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		referer = r.Header.Get("Referer")
+		if calls == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write(img)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	r := &Rehoster{Fetcher: testFetcher(t), Store: &DirStore{Dir: dir, BaseURL: "https://cdn.example/img/"}, MaxAttempts: 2}
+	u, err := r.Rehost(context.Background(), srv.URL+"/1.png", "https://site.example/manga/1", "7/001")
+	if err != nil {
+		t.Fatalf("rehost: %v", err)
+	}
+	if len(u) != 1 || u[0] != "https://cdn.example/img/7/001.png" {
+		t.Errorf("urls = %q", u)
+	}
+	if calls != 2 || referer != "https://site.example/manga/1" {
+		t.Errorf("calls=%d referer=%q", calls, referer)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "7", "001.png"))
+	if err != nil || !bytes.Equal(got, img) {
+		t.Errorf("stored file: %v", err)
+	}
+}
+
+func TestRehostRejectsNonImage(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = w.Write([]byte("<html>blocked</html>"))
+	}))
+	defer srv.Close()
+
+	r := &Rehoster{Fetcher: testFetcher(t), Store: &DirStore{Dir: t.TempDir(), BaseURL: "https://cdn.example"}, MaxAttempts: 3}
+	if _, err := r.Rehost(context.Background(), srv.URL, "", "1/001"); err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 1 {
+		t.Errorf("permanent error retried: calls=%d", calls)
+	}
+}
@@ -0,0 +1,74 @@
+// Package images перезаливает изображения страниц с сайта-источника
+// в собственное хранилище, чтобы страницы Telegraph не зависели от чужих CDN.
+package images
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	appErr "go_scripts/internal/errors"
+	"go_scripts/telegraph"
+)
+
+// Store сохраняет файл и возвращает его публичный URL.
+type Store interface {
+	Name() string
+	Put(ctx context.Context, name, contentType string, data []byte) (string, error)
+}
+
+// TelegraphStore загружает файлы на telegra.ph через /upload.
+type TelegraphStore struct {
+	Client *telegraph.Client
+}
+
+func (s *TelegraphStore) Name() string { return "telegraph" }
+
+func (s *TelegraphStore) Put(ctx context.Context, name, contentType string, data []byte) (string, error) {
+	return s.Client.Upload(ctx, name, contentType, data)
+}
+
+// DirStore складывает файлы в каталог, который раздаётся по BaseURL
+// (nginx, S3-совместимое хранилище, смонтированное как каталог, и т.п.).
+type DirStore struct {
+	Dir     string
+	BaseURL string
+}
+
+func (s *DirStore) Name() string { return "dir" }
+
+func (s *DirStore) Put(_ context.Context, name, _ string, data []byte) (string, error) {
+	dst := filepath.Join(s.Dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return "", appErr.NewInternalError("image store", err)
+	}
+	// пишем во временный файл, чтобы по URL никогда не отдавался недописанный файл
+	tmp := dst + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return "", appErr.NewInternalError("image store", err)
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		return "", appErr.NewInternalError("image store", err)
+	}
+	return strings.TrimRight(s.BaseURL, "/") + "/" + path.Clean(name), nil
+}
+
+// NewStore создаёт хранилище по имени из конфигурации: "telegraph" или "dir".
+// "none" (не перезаливать, ссылаться на источник) возвращает nil.
+func NewStore(kind string, tg *telegraph.Client, dir, baseURL string) (Store, error) {
+	switch kind {
+	case "", "telegraph":
+		return &TelegraphStore{Client: tg}, nil
+	case "dir":
+		if dir == "" || baseURL == "" {
+			return nil, appErr.NewValidationError("IMAGE_STORE=dir", "нужны IMAGE_STORE_DIR и IMAGE_STORE_BASE_URL")
+		}
+		return &DirStore{Dir: dir, BaseURL: baseURL}, nil
+	case "none":
+		return nil, nil
+	}
+	return nil, appErr.NewValidationError("Неверный IMAGE_STORE", fmt.Sprintf("неизвестное хранилище %q", kind))
+}
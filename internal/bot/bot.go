@@ -4,39 +4,92 @@ import (
 	"context"
 	"time"
 
+	"go_scripts/database"
 	"go_scripts/internal/fsm"
 	"go_scripts/internal/logger"
 	"go_scripts/internal/telegram"
 )
 
+// pollTimeout — сколько секунд Telegram держит запрос getUpdates.
+const pollTimeout = 25
+
+// Options — параметры обработки обновлений.
+type Options struct {
+	Workers      int           // одновременно обрабатываемых обновлений (разных чатов)
+	DrainTimeout time.Duration // сколько ждать обработки принятых обновлений при остановке
+}
+
 type Bot struct {
-	botURL  string
+	tg      *telegram.Client
 	handler *Handler
+	opts    Options
+}
+
+func New(tg *telegram.Client, manager *fsm.Manager, o Options) *Bot {
+	return &Bot{tg: tg, handler: NewHandler(tg, manager), opts: o}
+}
+
+func (b *Bot) newDispatcher(ctx context.Context) *dispatcher {
+	return newDispatcher(ctx, b.opts.Workers, b.handler.Handle)
 }
 
-func New(botURL string, manager *fsm.Manager) *Bot {
-	return &Bot{botURL: botURL, handler: NewHandler(botURL, manager)}
+// SyncCommands узнаёт имя бота (для команд вида /cmd@bot) и публикует меню
+// команд: всем — общедоступные, каждому администратору в личном чате — все.
+// Администраторы, добавленные позже, получат меню после перезапуска.
+func (b *Bot) SyncCommands(ctx context.Context) error {
+	me, err := b.tg.GetMe(ctx)
+	if err != nil {
+		return err
+	}
+	b.handler.router.SetUsername(me.Username)
+
+	r := b.handler.router
+	if err := b.tg.SetMyCommands(ctx, r.BotCommands(false), telegram.BotCommandScope{Type: "default"}); err != nil {
+		return err
+	}
+	admins, err := database.AdminList()
+	if err != nil {
+		return err
+	}
+	for _, adm := range admins {
+		scope := telegram.BotCommandScope{Type: "chat", ChatID: adm.TelegramUserID}
+		if err := b.tg.SetMyCommands(ctx, r.BotCommands(true), scope); err != nil {
+			logger.TelegramWarn("set commands for admin %d: %v", adm.TelegramUserID, err)
+		}
+	}
+	logger.BotInfo("commands synced for @%s, admins=%d", me.Username, len(admins))
+	return nil
 }
 
+// Run получает обновления long polling'ом. Оставшийся от webhook-режима
+// webhook удаляется, иначе getUpdates отвечает 409.
 func (b *Bot) Run(ctx context.Context) {
+	if err := b.tg.DeleteWebhook(ctx, false); err != nil {
+		logger.TelegramError("delete webhook: %v", err)
+	}
+	d := b.newDispatcher(ctx)
 	offset := 0
 	for {
 		select {
 		case <-ctx.Done():
+			d.drain(b.opts.DrainTimeout)
 			logger.BotInfo("bot run stopped")
 			return
 		default:
 		}
 
-		updates, err := telegram.GetUpdates(b.botURL, offset)
+		updates, err := b.tg.GetUpdates(ctx, offset, pollTimeout)
 		if err != nil {
+			if ctx.Err() != nil {
+				continue
+			}
 			logger.TelegramError("get updates failed: %v", err)
 			time.Sleep(2 * time.Second)
 			continue
 		}
 		for _, u := range updates {
 			offset = u.UpdateID + 1
-			b.handler.Handle(context.Background(), u)
+			d.dispatch(u)
 		}
 	}
 }
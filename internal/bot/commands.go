@@ -2,33 +2,235 @@ package bot
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
 
+	"go_scripts/database"
 	"go_scripts/internal/fsm"
 	"go_scripts/internal/logger"
-	"go_scripts/internal/telegram"
 )
 
-func (h *Handler) handleCommand(ctx context.Context, chatID int64, userID int, text string) {
-	switch text {
-	case "/start":
-		h.handleStart(ctx, chatID, userID)
-	case "/cancel":
-		h.handleCancel(ctx, chatID, userID)
-	default:
-		// ignore unknown commands for now
+// registerCommands — все команды бота; порядок — порядок в /help и меню.
+func (h *Handler) registerCommands() {
+	h.router.Register(Command{
+		Name:        "start",
+		Description: "Прислать ссылки на работы для парсинга",
+		Permission:  PermAdmin,
+		Handler:     func(ctx context.Context, c *CommandContext) { h.handleStart(ctx, c.ChatID, c.UserID) },
+	})
+	h.router.Register(Command{
+		Name:        "reparse",
+		Usage:       "[ссылка или id]",
+		Description: "Перепарсить опубликованную работу",
+		Permission:  PermAdmin,
+		MaxArgs:     1,
+		Handler: func(ctx context.Context, c *CommandContext) {
+			arg := ""
+			if len(c.Args) > 0 {
+				arg = c.Args[0]
+			}
+			h.handleReparse(ctx, c.ChatID, c.UserID, arg)
+		},
+	})
+	h.router.Register(Command{
+		Name:        "history",
+		Usage:       "<ссылка или id>",
+		Description: "История статусов записи",
+		Permission:  PermAdmin,
+		MinArgs:     1,
+		MaxArgs:     1,
+		Handler:     func(ctx context.Context, c *CommandContext) { h.handleHistory(ctx, c.ChatID, c.Args[0]) },
+	})
+	h.router.Register(Command{
+		Name:        "back",
+		Description: "Вернуться к предыдущему шагу диалога",
+		Permission:  PermAdmin,
+		Handler: func(ctx context.Context, c *CommandContext) {
+			// активный мастер перехватывает /back раньше роутера
+			_, _ = h.tg.Send(ctx, c.ChatID, "Нет активного диалога.")
+		},
+	})
+	h.router.Register(Command{
+		Name:        "cancel",
+		Description: "Отменить текущий диалог",
+		Permission:  PermAdmin,
+		Handler:     func(ctx context.Context, c *CommandContext) { h.handleCancel(ctx, c.ChatID, c.UserID) },
+	})
+	h.router.Register(Command{
+		Name:        "help",
+		Description: "Список команд",
+		Permission:  PermEveryone,
+		Handler: func(ctx context.Context, c *CommandContext) {
+			_, _ = h.tg.Send(ctx, c.ChatID, h.router.Help(c.IsAdmin))
+		},
+	})
+}
+
+func (h *Handler) handleCommand(ctx context.Context, chatID int64, userID int, isAdmin bool, text string) {
+	name, raw, ok := h.router.Parse(text)
+	if !ok {
+		return
+	}
+	cmd := h.router.Lookup(name)
+	switch {
+	case cmd == nil:
+		_, _ = h.tg.Send(ctx, chatID, "Неизвестная команда. /help — список команд")
+		return
+	case !cmd.allowed(isAdmin):
+		_, _ = h.tg.Send(ctx, chatID, "Бот доступен только администраторам.")
+		return
+	}
+	args := parseArgs(raw)
+	if !cmd.argsOK(len(args)) {
+		_, _ = h.tg.Send(ctx, chatID, "Использование: "+cmd.usage())
+		return
 	}
+	cmd.Handler(ctx, &CommandContext{ChatID: chatID, UserID: userID, IsAdmin: isAdmin, RawArgs: raw, Args: args})
 }
 
 func (h *Handler) handleStart(ctx context.Context, chatID int64, userID int) {
-	cur, _ := h.manager.Get(userID)
+	cur, _ := h.manager.Get(ctx, userID)
 	logger.UserInfo(userID, "/start prev_state=%v", cur)
-	h.manager.Set(userID, fsm.AwaitLink())
-	_ = telegram.SendMessage(h.botURL, chatID, "Привет! Пришли ссылку для парсера.")
+	if err := h.manager.Set(ctx, userID, fsm.AwaitLink()); err != nil {
+		_, _ = h.tg.Send(ctx, chatID, "Не удалось сохранить состояние, попробуйте позже")
+		return
+	}
+	_, _ = h.tg.Send(ctx, chatID, "Привет! Пришли ссылку для парсера.")
 }
 
 func (h *Handler) handleCancel(ctx context.Context, chatID int64, userID int) {
-	cur, _ := h.manager.Get(userID)
-	h.manager.Set(userID, fsm.Start())
+	cur, _ := h.manager.Get(ctx, userID)
+	if err := h.manager.Set(ctx, userID, fsm.Start()); err != nil {
+		_, _ = h.tg.Send(ctx, chatID, "Не удалось сохранить состояние, попробуйте позже")
+		return
+	}
 	logger.UserInfo(userID, "/cancel prev_state=%v", cur)
-	_ = telegram.SendMessage(h.botURL, chatID, "Отменено")
+	_, _ = h.tg.Send(ctx, chatID, "Отменено")
+}
+
+// handleReparse ставит опубликованную работу (по ссылке на источник или id)
+// в очередь перепарсинга; процессор обновит её страницы Telegraph на месте.
+// Без аргумента запускает мастер, который спросит ссылку или id.
+func (h *Handler) handleReparse(ctx context.Context, chatID int64, userID int, arg string) {
+	if arg == "" {
+		prompt, err := h.reparse.Start(ctx, h.manager, userID, reparseData{})
+		if err != nil {
+			_, _ = h.tg.Send(ctx, chatID, "Не удалось сохранить состояние, попробуйте позже")
+			return
+		}
+		_, _ = h.tg.Send(ctx, chatID, prompt)
+		return
+	}
+	c, err := findContent(arg)
+	if err != nil {
+		_, _ = h.tg.Send(ctx, chatID, "Не удалось найти запись, попробуйте позже")
+		return
+	}
+	if c == nil {
+		_, _ = h.tg.Send(ctx, chatID, "Запись не найдена.")
+		return
+	}
+	_, _ = h.tg.Send(ctx, chatID, requestReparse(userID, c))
+}
+
+// handleHistory показывает статус записи и последние переходы из content_events.
+func (h *Handler) handleHistory(ctx context.Context, chatID int64, arg string) {
+	c, err := findContent(arg)
+	if err != nil {
+		_, _ = h.tg.Send(ctx, chatID, "Не удалось найти запись, попробуйте позже")
+		return
+	}
+	if c == nil {
+		_, _ = h.tg.Send(ctx, chatID, "Запись не найдена.")
+		return
+	}
+	events, err := database.ContentHistory(c.ID)
+	if err != nil {
+		logger.DatabaseError("history id=%d: %v", c.ID, err)
+		_, _ = h.tg.Send(ctx, chatID, "Не удалось загрузить историю, попробуйте позже")
+		return
+	}
+	hosted, total, err := database.ContentImagesProgress(c.ID)
+	if err != nil {
+		logger.DatabaseError("images progress id=%d: %v", c.ID, err)
+	}
+	_, _ = h.tg.Send(ctx, chatID, formatHistory(c, events, hosted, total))
+}
+
+// historyLimit — сколько последних событий показывает /history, чтобы ответ
+// уместился в одно сообщение.
+const historyLimit = 20
+
+// formatHistory — статус записи, прогресс перезаливки изображений (hosted из
+// total; total=0 — перезаливки не было) и последние historyLimit событий в HTML.
+func formatHistory(c *database.Content, events []database.ContentEvent, hosted, total int64) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<b>#%d</b> %s — %s\n", c.ID, html.EscapeString(string(c.Status)), html.EscapeString(c.SourceURL))
+	if c.LastError != "" {
+		fmt.Fprintf(&b, "Ошибка: %s\n", html.EscapeString(truncate(c.LastError, 200)))
+	}
+	if total > 0 {
+		fmt.Fprintf(&b, "Изображения: перезалито %d из %d\n", hosted, total)
+	}
+	if len(events) > historyLimit {
+		fmt.Fprintf(&b, "… ещё %d событий\n", len(events)-historyLimit)
+		events = events[len(events)-historyLimit:]
+	}
+	for _, e := range events {
+		line := e.CreatedAt.Format("02.01 15:04") + " "
+		if e.FromStatus != e.ToStatus {
+			line += string(e.FromStatus) + " → " + string(e.ToStatus)
+		} else {
+			line += string(e.ToStatus)
+		}
+		line += " " + e.Actor
+		if e.Note != "" {
+			line += ": " + truncate(e.Note, 200)
+		}
+		b.WriteString(html.EscapeString(line))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// truncate обрезает s до n символов.
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n]) + "…"
+	}
+	return s
+}
+
+// findContent ищет запись по id или ссылке на источник; nil — не найдена.
+func findContent(arg string) (*database.Content, error) {
+	var c *database.Content
+	var err error
+	if id, perr := strconv.ParseUint(arg, 10, 64); perr == nil {
+		c, err = database.ContentGetByID(uint(id))
+	} else {
+		c, err = database.ContentGetByURL(arg)
+	}
+	if err != nil {
+		logger.DatabaseError("content lookup %q: %v", arg, err)
+		return nil, err
+	}
+	return c, nil
+}
+
+// requestReparse ставит запись в очередь и возвращает ответ администратору.
+func requestReparse(userID int, c *database.Content) string {
+	err := database.ContentRequestReparse(c.ID, adminActor(int64(userID)))
+	switch {
+	case errors.Is(err, database.ErrNotPublished):
+		return "Страница ещё не создана (статус: " + string(c.Status) + "), перепарсить нечего."
+	case err != nil:
+		logger.BotError("reparse %d: %v", c.ID, err)
+		return "Не удалось поставить в очередь, попробуйте позже"
+	default:
+		logger.UserInfo(userID, "/reparse id=%d", c.ID)
+		return "Перепарсинг поставлен в очередь, страница обновится по той же ссылке."
+	}
 }
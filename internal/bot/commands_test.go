@@ -0,0 +1,37 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"go_scripts/database"
+)
+
+func TestFormatHistory(t *testing.T) {
+	c := &database.Content{ID: 7, Status: database.StatusError, SourceURL: "https://x/manga/1", LastError: "<boom>"}
+	at := time.Date(2026, 10, 18, 12, 30, 0, 0, time.UTC)
+	var events []database.ContentEvent
+	for i := 0; i < historyLimit+2; i++ {
+		events = append(events, database.ContentEvent{FromStatus: database.StatusNew, ToStatus: database.StatusProcessing, Actor: fmt.Sprintf("processor:w/%d", i), CreatedAt: at})
+	}
+	events = append(events, database.ContentEvent{FromStatus: database.StatusParsed, ToStatus: database.StatusParsed, Actor: "admin:1", Note: "reparse requested", CreatedAt: at})
+
+	got := formatHistory(c, events, 5, 12)
+	for _, want := range []string{
+		"<b>#7</b> Error",
+		"Ошибка: &lt;boom&gt;",
+		"Изображения: перезалито 5 из 12",
+		"… ещё 3 событий",
+		"18.10 12:30 Parsed admin:1: reparse requested",
+		"New → Processing processor:w/21",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("history lacks %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "processor:w/2\n") {
+		t.Errorf("events beyond the limit must be skipped:\n%s", got)
+	}
+}
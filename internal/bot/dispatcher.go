@@ -0,0 +1,110 @@
+package bot
+
+import (
+	"context"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"go_scripts/internal/logger"
+	"go_scripts/internal/telegram"
+)
+
+// dispatcher обрабатывает обновления параллельно, но обновления одного чата —
+// строго по очереди. Обработчики получают собственный контекст: остановка
+// приёма обновлений их не прерывает, пока не истечёт время drain.
+type dispatcher struct {
+	handle func(context.Context, telegram.Update)
+	ctx    context.Context
+	cancel context.CancelFunc
+	sem    chan struct{} // одновременно обрабатываемых обновлений
+
+	mu     sync.Mutex
+	queues map[int64][]telegram.Update // очередь чата; ключ есть, пока чат обрабатывается
+	wg     sync.WaitGroup
+}
+
+func newDispatcher(runCtx context.Context, workers int, handle func(context.Context, telegram.Update)) *dispatcher {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(runCtx))
+	return &dispatcher{
+		handle: handle,
+		ctx:    ctx,
+		cancel: cancel,
+		sem:    make(chan struct{}, max(workers, 1)),
+		queues: map[int64][]telegram.Update{},
+	}
+}
+
+// dispatch ставит обновление в очередь его чата и не ждёт обработки.
+func (d *dispatcher) dispatch(u telegram.Update) {
+	key := chatKey(u)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	q, running := d.queues[key]
+	d.queues[key] = append(q, u)
+	if !running {
+		d.wg.Add(1)
+		go d.runChat(key)
+	}
+}
+
+func (d *dispatcher) runChat(key int64) {
+	defer d.wg.Done()
+	for {
+		d.mu.Lock()
+		q := d.queues[key]
+		if len(q) == 0 {
+			delete(d.queues, key)
+			d.mu.Unlock()
+			return
+		}
+		u := q[0]
+		d.queues[key] = q[1:]
+		d.mu.Unlock()
+
+		d.sem <- struct{}{}
+		d.run(u)
+		<-d.sem
+	}
+}
+
+// run обрабатывает одно обновление; паника не роняет бота и не останавливает
+// очередь чата.
+func (d *dispatcher) run(u telegram.Update) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.BotError("panic handling update %d: %v\n%s", u.UpdateID, r, debug.Stack())
+		}
+	}()
+	d.handle(d.ctx, u)
+}
+
+// drain ждёт обработки уже принятых обновлений; по истечении timeout
+// отменяет контекст обработчиков и дожидается их выхода.
+func (d *dispatcher) drain(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		logger.Warn("BOT", "drain timeout, aborting in-flight updates")
+		d.cancel()
+		<-done
+	}
+	d.cancel()
+}
+
+// chatKey — чат, в рамках которого сохраняется порядок обновлений.
+// Для callback-кнопок это пользователь: ему же отвечает Handler.
+func chatKey(u telegram.Update) int64 {
+	switch {
+	case u.Message != nil:
+		return u.Message.Chat.ID
+	case u.Callback != nil:
+		return u.Callback.From.ID
+	}
+	return 0
+}
@@ -0,0 +1,92 @@
+package bot
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go_scripts/internal/telegram"
+)
+
+func msgUpdate(id int, chat int64) telegram.Update {
+	return telegram.Update{UpdateID: id, Message: &telegram.Message{Chat: telegram.Chat{ID: chat}}}
+}
+
+func TestDispatcherKeepsOrderPerChat(t *testing.T) {
+	var mu sync.Mutex
+	got := map[int64][]int{}
+	d := newDispatcher(context.Background(), 4, func(_ context.Context, u telegram.Update) {
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		got[u.Message.Chat.ID] = append(got[u.Message.Chat.ID], u.UpdateID)
+		mu.Unlock()
+	})
+	for i := 0; i < 30; i++ {
+		d.dispatch(msgUpdate(i, int64(i%3)))
+	}
+	d.drain(5 * time.Second)
+	for chat, ids := range got {
+		if len(ids) != 10 {
+			t.Fatalf("chat %d handled %d updates", chat, len(ids))
+		}
+		for i := 1; i < len(ids); i++ {
+			if ids[i] < ids[i-1] {
+				t.Fatalf("chat %d out of order: %v", chat, ids)
+			}
+		}
+	}
+}
+
+func TestDispatcherRunsChatsConcurrently(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan int64, 2)
+	d := newDispatcher(context.Background(), 2, func(_ context.Context, u telegram.Update) {
+		started <- u.Message.Chat.ID
+		<-release
+	})
+	d.dispatch(msgUpdate(1, 1))
+	d.dispatch(msgUpdate(2, 2))
+	for i := 0; i < 2; i++ {
+		select {
+		case <-started:
+		case <-time.After(time.Second):
+			t.Fatal("slow chat blocks the other one")
+		}
+	}
+	close(release)
+	d.drain(time.Second)
+}
+
+func TestDispatcherRecoversPanicAndDrains(t *testing.T) {
+	runCtx, stop := context.WithCancel(context.Background())
+	var handled []int
+	d := newDispatcher(runCtx, 1, func(ctx context.Context, u telegram.Update) {
+		if u.UpdateID == 1 {
+			panic("boom")
+		}
+		if ctx.Err() != nil {
+			t.Errorf("handler context cancelled by run context")
+		}
+		handled = append(handled, u.UpdateID)
+	})
+	d.dispatch(msgUpdate(1, 7))
+	d.dispatch(msgUpdate(2, 7))
+	stop()
+	d.drain(time.Second)
+	if len(handled) != 1 || handled[0] != 2 {
+		t.Fatalf("handled = %v, want [2]", handled)
+	}
+}
+
+func TestDispatcherDrainTimeoutCancelsHandlers(t *testing.T) {
+	d := newDispatcher(context.Background(), 1, func(ctx context.Context, _ telegram.Update) {
+		<-ctx.Done()
+	})
+	d.dispatch(msgUpdate(1, 1))
+	start := time.Now()
+	d.drain(50 * time.Millisecond)
+	if time.Since(start) > time.Second {
+		t.Fatal("drain did not cancel stuck handler")
+	}
+}
@@ -0,0 +1,47 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go_scripts/database"
+	"go_scripts/internal/fsm"
+)
+
+// reparseData — данные мастера /reparse.
+type reparseData struct {
+	ContentID uint `json:"content_id"`
+}
+
+// newReparseFlow — /reparse без аргумента: спрашивает ссылку или id работы.
+func newReparseFlow() *fsm.Flow[reparseData] {
+	return &fsm.Flow[reparseData]{
+		Name:    "reparse",
+		Timeout: 10 * time.Minute,
+		Steps: []fsm.Step[reparseData]{{
+			Name: "target",
+			Prompt: func(*reparseData) string {
+				return "Пришлите ссылку на работу или её id. /cancel — отмена."
+			},
+			Parse: func(_ context.Context, d *reparseData, input string) error {
+				c, err := findContent(strings.TrimSpace(input))
+				if err != nil {
+					return err
+				}
+				if c == nil {
+					return fsm.InputError("Запись не найдена.")
+				}
+				d.ContentID = c.ID
+				return nil
+			},
+		}},
+		Finish: func(_ context.Context, userID int, d *reparseData) (string, error) {
+			c, err := database.ContentGetByID(d.ContentID)
+			if err != nil || c == nil {
+				return "Запись не найдена.", err
+			}
+			return requestReparse(userID, c), nil
+		},
+	}
+}
@@ -2,23 +2,32 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"strconv"
 	"strings"
 	"time"
 
 	"go_scripts/database"
 	"go_scripts/internal/fsm"
+	"go_scripts/internal/logger"
 	"go_scripts/internal/scheduler"
 	"go_scripts/internal/telegram"
 )
 
 type Handler struct {
-	botURL  string
+	tg      *telegram.Client
 	manager *fsm.Manager
+	router  *Router
+	reparse *fsm.Flow[reparseData]
+	// flows — активные мастера по типу состояния пользователя
+	flows map[fsm.StateType]fsm.Runner
 }
 
-func NewHandler(botURL string, manager *fsm.Manager) *Handler {
-	return &Handler{botURL: botURL, manager: manager}
+func NewHandler(tg *telegram.Client, manager *fsm.Manager) *Handler {
+	h := &Handler{tg: tg, manager: manager, router: NewRouter(), reparse: newReparseFlow()}
+	h.flows = map[fsm.StateType]fsm.Runner{h.reparse.Type(): h.reparse}
+	h.registerCommands()
+	return h
 }
 
 func (h *Handler) Handle(ctx context.Context, u telegram.Update) {
@@ -41,17 +50,35 @@ func (h *Handler) Handle(ctx context.Context, u telegram.Update) {
 	if u.Message.From != nil {
 		tgUserID = u.Message.From.ID
 	}
-	if ok, _ := database.AdminExists(tgUserID); !ok {
-		_ = telegram.SendMessage(h.botURL, chatID, "Бот доступен только администраторам.")
+	// Commands check their own permission level; everything else is admin-only
+	isAdmin, _ := database.AdminExists(tgUserID)
+	if !isAdmin && !strings.HasPrefix(text, "/") {
+		_, _ = h.tg.Send(ctx, chatID, "Бот доступен только администраторам.")
 		return
 	}
 
+	var state fsm.State
+	if isAdmin {
+		state, _ = h.manager.Get(ctx, userID)
+	}
+	if flow, ok := h.flows[state.Type]; ok {
+		// /back (в том числе /back@bot) — шаг назад в мастере, прочие команды
+		// выполняются как обычно
+		name, _, isCmd := h.router.Parse(text)
+		switch {
+		case !strings.HasPrefix(text, "/"):
+			h.handleFlow(ctx, chatID, userID, flow, text)
+			return
+		case isCmd && name == "back":
+			h.handleFlow(ctx, chatID, userID, flow, fsm.BackCommand)
+			return
+		}
+	}
 	if strings.HasPrefix(text, "/") {
-		h.handleCommand(ctx, chatID, userID, text)
+		h.handleCommand(ctx, chatID, userID, isAdmin, text)
 		return
 	}
 
-	state, _ := h.manager.Get(userID)
 	switch state.Type {
 	case fsm.StateAwaitLink:
 		h.handleAwaitLink(ctx, chatID, userID, text)
@@ -60,6 +87,17 @@ func (h *Handler) Handle(ctx context.Context, u telegram.Update) {
 	}
 }
 
+// handleFlow передаёт ответ активному мастеру и отправляет его реплику.
+func (h *Handler) handleFlow(ctx context.Context, chatID int64, userID int, flow fsm.Runner, text string) {
+	reply, err := flow.Handle(ctx, h.manager, userID, text)
+	if err != nil {
+		logger.BotError("flow %s user=%d: %v", flow.Type(), userID, err)
+		_, _ = h.tg.Send(ctx, chatID, "Что-то пошло не так, попробуйте позже или /cancel")
+		return
+	}
+	_, _ = h.tg.Send(ctx, chatID, reply)
+}
+
 func (h *Handler) handleCallback(ctx context.Context, cb telegram.CallbackQuery) {
 	userID := int(cb.From.ID)
 	chatID := cb.From.ID
@@ -69,6 +107,7 @@ func (h *Handler) handleCallback(ctx context.Context, cb telegram.CallbackQuery)
 		return
 	}
 	data := cb.Data
+	actor := adminActor(cb.From.ID)
 	if strings.HasPrefix(data, "confirm:") {
 		idStr := strings.TrimPrefix(data, "confirm:")
 		if id, err := strconv.ParseUint(idStr, 10, 64); err == nil {
@@ -78,16 +117,36 @@ func (h *Handler) handleCallback(ctx context.Context, cb telegram.CallbackQuery)
 				base = *last
 			}
 			sched := scheduler.NextMoscowSlotAfter(base)
-			_ = database.ContentMarkConfirmedAndSchedule(uint(id), sched)
-			_ = telegram.SendMessage(h.botURL, chatID, "Пост подтвержден и поставлен в очередь")
+			if err := database.ContentMarkConfirmedAndSchedule(uint(id), sched, actor); err != nil {
+				h.reportTransitionError(ctx, chatID, uint(id), err)
+				return
+			}
+			_, _ = h.tg.Send(ctx, chatID, "Пост подтвержден и поставлен в очередь")
 			// reset user state optionally
 			_ = userID // keep for linter if unused
 		}
 	} else if strings.HasPrefix(data, "reject:") {
 		idStr := strings.TrimPrefix(data, "reject:")
 		if id, err := strconv.ParseUint(idStr, 10, 64); err == nil {
-			_ = database.ContentMarkCancelled(uint(id))
-			_ = telegram.SendMessage(h.botURL, chatID, "Пост отклонен")
+			if err := database.ContentMarkCancelled(uint(id), actor); err != nil {
+				h.reportTransitionError(ctx, chatID, uint(id), err)
+				return
+			}
+			_, _ = h.tg.Send(ctx, chatID, "Пост отклонен")
 		}
 	}
 }
+
+// reportTransitionError tells the admin why a stale button did nothing
+func (h *Handler) reportTransitionError(ctx context.Context, chatID int64, id uint, err error) {
+	if errors.Is(err, database.ErrInvalidTransition) {
+		status := "неизвестен"
+		if c, _ := database.ContentGetByID(id); c != nil {
+			status = string(c.Status)
+		}
+		_, _ = h.tg.Send(ctx, chatID, "Пост уже обработан (статус: "+status+")")
+		return
+	}
+	logger.BotError("content %d transition: %v", id, err)
+	_, _ = h.tg.Send(ctx, chatID, "Не удалось обновить пост, попробуйте позже")
+}
@@ -0,0 +1,163 @@
+package bot
+
+import (
+	"context"
+	"html"
+	"strings"
+	"unicode"
+
+	"go_scripts/internal/telegram"
+)
+
+// Permission — кому доступна команда.
+type Permission int
+
+const (
+	PermEveryone Permission = iota
+	PermAdmin
+)
+
+// CommandContext — разобранный вызов команды.
+type CommandContext struct {
+	ChatID  int64
+	UserID  int
+	IsAdmin bool
+	RawArgs string   // текст после команды как есть
+	Args    []string // аргументы через пробел; "в кавычках" — один аргумент
+}
+
+// Command — команда бота для Router.
+type Command struct {
+	Name        string // без "/", например "reparse"
+	Usage       string // аргументы для /help, например "<ссылка или id>"
+	Description string
+	Permission  Permission
+	MinArgs     int
+	MaxArgs     int // 0 — не проверять
+	Handler     func(ctx context.Context, c *CommandContext)
+}
+
+// Router — реестр команд: разбор "/cmd@bot args", проверка прав и числа
+// аргументов, текст /help и меню для setMyCommands.
+type Router struct {
+	username string // имя бота без @; пусто — принимаются команды для любого бота
+	commands map[string]*Command
+	order    []*Command // порядок регистрации — порядок в /help и меню
+}
+
+func NewRouter() *Router {
+	return &Router{commands: map[string]*Command{}}
+}
+
+// SetUsername задаёт имя бота: команды вида /cmd@other_bot игнорируются.
+func (r *Router) SetUsername(name string) { r.username = strings.TrimPrefix(name, "@") }
+
+// Register добавляет команду; повторная регистрация заменяет прежнюю.
+func (r *Router) Register(c Command) {
+	c.Name = strings.ToLower(c.Name)
+	if old, ok := r.commands[c.Name]; ok {
+		*old = c
+		return
+	}
+	r.commands[c.Name] = &c
+	r.order = append(r.order, &c)
+}
+
+func (r *Router) Lookup(name string) *Command { return r.commands[name] }
+
+// Parse выделяет имя команды и аргументы. ok=false — это не команда
+// или команда адресована другому боту.
+func (r *Router) Parse(text string) (name, rawArgs string, ok bool) {
+	if !strings.HasPrefix(text, "/") {
+		return "", "", false
+	}
+	head, rest, _ := strings.Cut(text[1:], " ")
+	if i := strings.IndexAny(head, "\n\t"); i >= 0 {
+		head, rest = head[:i], head[i+1:]+" "+rest
+	}
+	head, mention, _ := strings.Cut(head, "@")
+	if mention != "" && r.username != "" && !strings.EqualFold(mention, r.username) {
+		return "", "", false
+	}
+	if head == "" {
+		return "", "", false
+	}
+	return strings.ToLower(head), strings.TrimSpace(rest), true
+}
+
+// allowed сообщает, доступна ли команда пользователю.
+func (c *Command) allowed(isAdmin bool) bool {
+	return c.Permission == PermEveryone || isAdmin
+}
+
+// argsOK проверяет число аргументов.
+func (c *Command) argsOK(n int) bool {
+	return n >= c.MinArgs && (c.MaxArgs == 0 || n <= c.MaxArgs)
+}
+
+// usage — "/cmd <args>" в HTML.
+func (c *Command) usage() string {
+	u := "/" + c.Name
+	if c.Usage != "" {
+		u += " " + c.Usage
+	}
+	return html.EscapeString(u)
+}
+
+// Help — список доступных пользователю команд в HTML.
+func (r *Router) Help(isAdmin bool) string {
+	var b strings.Builder
+	b.WriteString("<b>Команды:</b>\n")
+	for _, c := range r.order {
+		if !c.allowed(isAdmin) {
+			continue
+		}
+		b.WriteString(c.usage())
+		b.WriteString(" — ")
+		b.WriteString(html.EscapeString(c.Description))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// BotCommands — меню команд для setMyCommands с учётом прав.
+func (r *Router) BotCommands(isAdmin bool) []telegram.BotCommand {
+	var out []telegram.BotCommand
+	for _, c := range r.order {
+		if c.allowed(isAdmin) {
+			out = append(out, telegram.BotCommand{Command: c.Name, Description: c.Description})
+		}
+	}
+	return out
+}
+
+// parseArgs делит строку аргументов по пробелам; текст в двойных кавычках —
+// один аргумент.
+func parseArgs(s string) []string {
+	var (
+		args   []string
+		cur    strings.Builder
+		quoted bool
+		inArg  bool
+	)
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inArg = true
+		case unicode.IsSpace(r) && !quoted:
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args
+}
@@ -0,0 +1,87 @@
+package bot
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testRouter() *Router {
+	r := NewRouter()
+	noop := func(context.Context, *CommandContext) {}
+	r.Register(Command{Name: "start", Description: "Начать", Permission: PermAdmin, Handler: noop})
+	r.Register(Command{Name: "reparse", Usage: "<id>", Description: "Перепарсить", Permission: PermAdmin, MinArgs: 1, MaxArgs: 1, Handler: noop})
+	r.Register(Command{Name: "help", Description: "Помощь", Handler: noop})
+	return r
+}
+
+func TestRouterParse(t *testing.T) {
+	r := testRouter()
+	r.SetUsername("@niko_bot")
+	for _, tc := range []struct {
+		text, name, args string
+		ok               bool
+	}{
+		{"/start", "start", "", true},
+		{"/Start@Niko_Bot", "start", "", true},
+		{"/reparse@niko_bot 42", "reparse", "42", true},
+		{"/reparse   https://a/b  ", "reparse", "https://a/b", true},
+		{"/reparse\n42", "reparse", "42", true},
+		{"/start@other_bot", "", "", false},
+		{"hello", "", "", false},
+		{"/", "", "", false},
+	} {
+		name, args, ok := r.Parse(tc.text)
+		if name != tc.name || args != tc.args || ok != tc.ok {
+			t.Errorf("Parse(%q) = %q, %q, %v", tc.text, name, args, ok)
+		}
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	got := parseArgs(`one  "two words" three ""`)
+	want := []string{"one", "two words", "three", ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("parseArgs = %q", got)
+	}
+	if got := parseArgs("   "); got != nil {
+		t.Fatalf("empty = %q", got)
+	}
+}
+
+func TestCommandArgsAndPermissions(t *testing.T) {
+	r := testRouter()
+	re := r.Lookup("reparse")
+	if re.argsOK(0) || !re.argsOK(1) || re.argsOK(2) {
+		t.Fatal("reparse takes exactly one argument")
+	}
+	if !r.Lookup("start").argsOK(3) {
+		t.Fatal("MaxArgs 0 must not limit arguments")
+	}
+	if re.allowed(false) || !re.allowed(true) || !r.Lookup("help").allowed(false) {
+		t.Fatal("permission check")
+	}
+}
+
+func TestRouterHelpAndMenu(t *testing.T) {
+	r := testRouter()
+	user := r.Help(false)
+	if strings.Contains(user, "/start") || !strings.Contains(user, "/help — Помощь") {
+		t.Fatalf("user help:\n%s", user)
+	}
+	admin := r.Help(true)
+	if !strings.Contains(admin, "/reparse &lt;id&gt; — Перепарсить") {
+		t.Fatalf("admin help:\n%s", admin)
+	}
+	var names []string
+	for _, c := range r.BotCommands(true) {
+		names = append(names, c.Command)
+	}
+	if !reflect.DeepEqual(names, []string{"start", "reparse", "help"}) {
+		t.Fatalf("menu = %v", names)
+	}
+	if got := r.BotCommands(false); len(got) != 1 || got[0].Command != "help" {
+		t.Fatalf("public menu = %v", got)
+	}
+}
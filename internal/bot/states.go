@@ -4,18 +4,23 @@ import (
 	"context"
 
 	"go_scripts/database"
-	"go_scripts/internal/telegram"
+	"go_scripts/parsers"
 )
 
 func (h *Handler) handleAwaitLink(ctx context.Context, chatID int64, userID int, text string) {
 	if !looksLikeHTTPURL(text) {
-		_ = telegram.SendMessage(h.botURL, chatID, "Пришлите корректную ссылку (http/https).")
+		_, _ = h.tg.Send(ctx, chatID, "Пришлите корректную ссылку (http/https).")
+		return
+	}
+	parser, err := parsers.Lookup(text)
+	if err != nil {
+		_, _ = h.tg.Send(ctx, chatID, "Этот сайт пока не поддерживается.")
 		return
 	}
 	if exists, _ := database.ContentExistsByURL(text); exists {
-		_ = telegram.SendMessage(h.botURL, chatID, "Такая ссылка уже есть в базе.")
+		_, _ = h.tg.Send(ctx, chatID, "Такая ссылка уже есть в базе.")
 		return
 	}
-	_ = telegram.SendMessage(h.botURL, chatID, "Обрабатываю ссылку...")
-	_, _ = database.ContentCreateNew(text)
+	_, _ = h.tg.Send(ctx, chatID, "Обрабатываю ссылку...")
+	_, _ = database.ContentCreateNew(parser.Name(), text, adminActor(int64(userID)))
 }
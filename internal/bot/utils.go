@@ -1,5 +1,12 @@
 package bot
 
+import "strconv"
+
 func looksLikeHTTPURL(s string) bool {
 	return len(s) > 7 && (s[:7] == "http://" || (len(s) > 8 && s[:8] == "https://"))
 }
+
+// adminActor identifies an administrator in content_events
+func adminActor(userID int64) string {
+	return "admin:" + strconv.FormatInt(userID, 10)
+}
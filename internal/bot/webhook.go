@@ -0,0 +1,104 @@
+package bot
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go_scripts/internal/logger"
+	"go_scripts/internal/telegram"
+)
+
+// WebhookOptions — настройки приёма обновлений через webhook.
+type WebhookOptions struct {
+	URL    string // публичный HTTPS-адрес, который регистрируется в setWebhook
+	Listen string // адрес HTTP-сервера, например :8080 (TLS — на reverse proxy)
+	Path   string // путь обработчика; пусто — путь из URL
+	Secret string // secret_token: Telegram присылает его в каждом запросе
+}
+
+// shutdownTimeout — сколько ждать завершения запросов при остановке.
+const shutdownTimeout = 10 * time.Second
+
+// RunWebhook регистрирует webhook, принимает обновления HTTP-сервером и
+// передаёт их в тот же Handler.Handle, что и Run. При остановке ctx сервер
+// завершает текущие запросы, webhook удаляется, а принятые обновления
+// дообрабатываются. Если HTTP-сервер не запустился или упал (например, адрес
+// занят), возвращается его ошибка.
+func (b *Bot) RunWebhook(ctx context.Context, o WebhookOptions) error {
+	path := o.Path
+	if path == "" {
+		u, err := url.Parse(o.URL)
+		if err != nil {
+			return err
+		}
+		path = u.Path
+	}
+	if path == "" {
+		path = "/"
+	}
+
+	d := b.newDispatcher(ctx)
+	mux := http.NewServeMux()
+	mux.Handle(path, b.webhookHandler(o.Secret, d.dispatch))
+	srv := &http.Server{Addr: o.Listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.ListenAndServe() }()
+
+	if err := b.tg.SetWebhook(ctx, telegram.WebhookParams{URL: o.URL, SecretToken: o.Secret}); err != nil {
+		_ = srv.Close()
+		d.drain(b.opts.DrainTimeout)
+		return err
+	}
+	logger.BotInfo("webhook set, listening on %s%s", o.Listen, path)
+
+	var runErr error
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, http.ErrServerClosed) {
+			runErr = err
+		}
+	case <-ctx.Done():
+	}
+
+	sctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(sctx); err != nil {
+		logger.BotError("webhook server shutdown: %v", err)
+	}
+	if err := b.tg.DeleteWebhook(sctx, false); err != nil {
+		logger.TelegramError("delete webhook: %v", err)
+	}
+	d.drain(b.opts.DrainTimeout)
+	logger.BotInfo("bot webhook stopped")
+	return runErr
+}
+
+// webhookHandler проверяет secret_token и передаёт обновление в dispatch.
+// Ответ 200 означает, что обновление принято; на ошибку Telegram пришлёт
+// его повторно.
+func (b *Bot) webhookHandler(secret string, dispatch func(telegram.Update)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		got := r.Header.Get(telegram.SecretTokenHeader)
+		if subtle.ConstantTimeCompare([]byte(got), []byte(secret)) != 1 {
+			logger.BotError("webhook: wrong secret token from %s", r.RemoteAddr)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var u telegram.Update
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&u); err != nil {
+			// повтор не поможет, поэтому подтверждаем получение
+			logger.BotError("webhook: bad update: %v", err)
+			return
+		}
+		dispatch(u)
+	})
+}
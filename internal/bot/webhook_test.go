@@ -0,0 +1,68 @@
+package bot
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go_scripts/internal/telegram"
+)
+
+func TestWebhookRejectsWrongSecret(t *testing.T) {
+	h := (&Bot{}).webhookHandler("s3cret", func(telegram.Update) {})
+	for _, tc := range []struct {
+		method, secret string
+		want           int
+	}{
+		{http.MethodGet, "s3cret", http.StatusMethodNotAllowed},
+		{http.MethodPost, "", http.StatusUnauthorized},
+		{http.MethodPost, "wrong", http.StatusUnauthorized},
+		{http.MethodPost, "s3cre", http.StatusUnauthorized},
+	} {
+		r := httptest.NewRequest(tc.method, "/hook", strings.NewReader(`{"update_id":1}`))
+		if tc.secret != "" {
+			r.Header.Set(telegram.SecretTokenHeader, tc.secret)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tc.want {
+			t.Errorf("%s secret=%q: status %d, want %d", tc.method, tc.secret, w.Code, tc.want)
+		}
+	}
+}
+
+func TestWebhookAcknowledgesBadJSON(t *testing.T) {
+	h := (&Bot{}).webhookHandler("s3cret", func(telegram.Update) {})
+	r := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(`{`))
+	r.Header.Set(telegram.SecretTokenHeader, "s3cret")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200", w.Code)
+	}
+}
+
+func TestRunWebhookReturnsListenError(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"ok":true,"result":true}`))
+	}))
+	t.Cleanup(api.Close)
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = busy.Close() })
+
+	tg := telegram.NewClient(telegram.Config{APIURL: api.URL + "/bot", Token: "tok", Limits: &telegram.RateLimits{}})
+	b := &Bot{tg: tg, handler: &Handler{}, opts: Options{Workers: 1, DrainTimeout: time.Second}}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = b.RunWebhook(ctx, WebhookOptions{URL: "https://example.com/hook", Listen: busy.Addr().String(), Secret: "s"})
+	if err == nil || ctx.Err() != nil {
+		t.Fatalf("want listen error before timeout, got %v", err)
+	}
+}
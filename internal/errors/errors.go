@@ -1,9 +1,11 @@
 package errors
 
 import (
+	stderrors "errors"
 	"fmt"
 	"runtime"
 	"strings"
+	"time"
 )
 
 type ErrorType string
@@ -26,6 +28,10 @@ type AppError struct {
 	Context map[string]interface{}
 	Inner   error
 	Stack   string
+	// Transient — ошибка временная (таймаут, 5xx, 429, flood), операцию стоит повторить.
+	Transient bool
+	// RetryAfter — сколько подождать перед повтором, если источник это сообщил.
+	RetryAfter time.Duration
 }
 
 func (e *AppError) Error() string {
@@ -73,6 +79,28 @@ func (e *AppError) WithContext(key string, value interface{}) *AppError {
 }
 func (e *AppError) WithUserMessage(m string) *AppError { e.UserMsg = m; return e }
 func (e *AppError) WithCode(c string) *AppError        { e.Code = c; return e }
+func (e *AppError) WithTransient(t bool) *AppError     { e.Transient = t; return e }
+func (e *AppError) WithRetryAfter(d time.Duration) *AppError {
+	e.Transient = true
+	e.RetryAfter = d
+	return e
+}
+
+// IsTransient сообщает, стоит ли повторить операцию, завершившуюся err.
+// Ошибки, не являющиеся AppError, считаются постоянными.
+func IsTransient(err error) bool {
+	var a *AppError
+	return stderrors.As(err, &a) && a.Transient
+}
+
+// RetryAfterOf возвращает рекомендованную паузу перед повтором или 0.
+func RetryAfterOf(err error) time.Duration {
+	var a *AppError
+	if stderrors.As(err, &a) {
+		return a.RetryAfter
+	}
+	return 0
+}
 
 func getStackTrace() string {
 	buf := make([]byte, 1024)
@@ -0,0 +1,210 @@
+package fsm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	appErr "go_scripts/internal/errors"
+)
+
+const (
+	// BackCommand возвращает мастер к предыдущему шагу.
+	BackCommand = "/back"
+	// CancelCommand прерывает мастер.
+	CancelCommand = "/cancel"
+	// Finish — значение Step.Next, завершающее мастер досрочно.
+	Finish = "$finish"
+)
+
+// ErrNoFlow — у пользователя нет активного мастера этого типа.
+var ErrNoFlow = errors.New("no active flow")
+
+// InputError — ответ не прошёл проверку шага. Текст показывается
+// пользователю, шаг повторяется.
+type InputError string
+
+func (e InputError) Error() string { return string(e) }
+
+// Step — шаг мастера с данными типа D.
+type Step[D any] struct {
+	Name   string
+	Prompt func(d *D) string
+	// Parse проверяет ответ и записывает его в d. InputError повторяет шаг,
+	// другие ошибки возвращаются вызывающему.
+	Parse func(ctx context.Context, d *D, input string) error
+	// Next выбирает следующий шаг по имени; nil или "" — следующий по порядку,
+	// Finish — завершение.
+	Next func(d *D) string
+}
+
+// Flow — многошаговый диалог (мастер). Прогресс и данные D хранятся в
+// State через Manager, поэтому с PostgresStore мастер переживает перезапуск;
+// D должен сериализоваться в JSON.
+type Flow[D any] struct {
+	Name    string
+	Steps   []Step[D]
+	Timeout time.Duration // без ответа дольше — мастер сбрасывается; 0 — без ограничения
+	// Finish вызывается после последнего шага и возвращает итоговое сообщение.
+	// Обязателен: без него Start возвращает ошибку.
+	Finish func(ctx context.Context, userID int, d *D) (string, error)
+}
+
+// Runner — мастер без параметра типа, чтобы хранить разные мастера в одном
+// реестре по типу состояния.
+type Runner interface {
+	Type() StateType
+	Handle(ctx context.Context, m *Manager, userID int, input string) (string, error)
+}
+
+// progress — состояние мастера в State.Data["flow"].
+type progress struct {
+	Step     string          `json:"step"`
+	History  []string        `json:"history,omitempty"`
+	Data     json.RawMessage `json:"data"`
+	Deadline time.Time       `json:"deadline"`
+}
+
+func (f *Flow[D]) Type() StateType { return StateType("flow:" + f.Name) }
+
+// Start запускает мастер с начальными данными d и возвращает первый вопрос.
+func (f *Flow[D]) Start(ctx context.Context, m *Manager, userID int, d D) (string, error) {
+	if err := f.validate(); err != nil {
+		return "", err
+	}
+	step := f.Steps[0]
+	if err := f.save(ctx, m, userID, &progress{Step: step.Name}, &d); err != nil {
+		return "", err
+	}
+	return step.Prompt(&d), nil
+}
+
+// validate проверяет объявление мастера, чтобы ошибка в нём не всплыла
+// паникой на последнем шаге.
+func (f *Flow[D]) validate() error {
+	if len(f.Steps) == 0 {
+		return appErr.NewInternalError("flow "+f.Name+" has no steps", nil)
+	}
+	if f.Finish == nil {
+		return appErr.NewInternalError("flow "+f.Name+" has no Finish", nil)
+	}
+	return nil
+}
+
+// Handle обрабатывает ответ пользователя на текущем шаге и возвращает
+// следующее сообщение: следующий вопрос, повтор вопроса с ошибкой проверки
+// или итог Finish.
+func (f *Flow[D]) Handle(ctx context.Context, m *Manager, userID int, input string) (string, error) {
+	st, ok := m.Get(ctx, userID)
+	if !ok || st.Type != f.Type() {
+		return "", ErrNoFlow
+	}
+	if err := f.validate(); err != nil {
+		return "", err
+	}
+	var p progress
+	raw, _ := st.Data["flow"].(string)
+	if err := json.Unmarshal([]byte(raw), &p); err != nil {
+		return "", appErr.NewInternalError("decode flow "+f.Name, err)
+	}
+	var d D
+	if len(p.Data) > 0 {
+		if err := json.Unmarshal(p.Data, &d); err != nil {
+			return "", appErr.NewInternalError("decode flow "+f.Name+" data", err)
+		}
+	}
+	idx := f.index(p.Step)
+	if idx < 0 {
+		return "", appErr.NewInternalError("flow "+f.Name+": unknown step "+p.Step, nil)
+	}
+
+	if !p.Deadline.IsZero() && time.Now().After(p.Deadline) {
+		if err := m.Set(ctx, userID, Start()); err != nil {
+			return "", err
+		}
+		return "Время ожидания ответа истекло, начните заново.", nil
+	}
+	switch input {
+	case CancelCommand:
+		if err := m.Set(ctx, userID, Start()); err != nil {
+			return "", err
+		}
+		return "Отменено", nil
+	case BackCommand:
+		if len(p.History) == 0 {
+			return f.Steps[idx].Prompt(&d), nil
+		}
+		p.Step = p.History[len(p.History)-1]
+		p.History = p.History[:len(p.History)-1]
+		if err := f.save(ctx, m, userID, &p, &d); err != nil {
+			return "", err
+		}
+		return f.Steps[f.index(p.Step)].Prompt(&d), nil
+	}
+
+	step := f.Steps[idx]
+	if err := step.Parse(ctx, &d, input); err != nil {
+		var ie InputError
+		if !errors.As(err, &ie) {
+			return "", err
+		}
+		if err := f.save(ctx, m, userID, &p, &d); err != nil {
+			return "", err
+		}
+		return string(ie) + "\n\n" + step.Prompt(&d), nil
+	}
+
+	next := ""
+	if step.Next != nil {
+		next = step.Next(&d)
+	}
+	if next == "" {
+		next = Finish
+		if idx+1 < len(f.Steps) {
+			next = f.Steps[idx+1].Name
+		}
+	}
+	if next == Finish {
+		if err := m.Set(ctx, userID, Start()); err != nil {
+			return "", err
+		}
+		return f.Finish(ctx, userID, &d)
+	}
+	ni := f.index(next)
+	if ni < 0 {
+		return "", appErr.NewInternalError("flow "+f.Name+": unknown step "+next, nil)
+	}
+	p.History = append(p.History, p.Step)
+	p.Step = next
+	if err := f.save(ctx, m, userID, &p, &d); err != nil {
+		return "", err
+	}
+	return f.Steps[ni].Prompt(&d), nil
+}
+
+func (f *Flow[D]) index(name string) int {
+	for i, s := range f.Steps {
+		if s.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// save сохраняет прогресс и продлевает срок ответа.
+func (f *Flow[D]) save(ctx context.Context, m *Manager, userID int, p *progress, d *D) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return appErr.NewInternalError("encode flow "+f.Name+" data", err)
+	}
+	p.Data = data
+	if f.Timeout > 0 {
+		p.Deadline = time.Now().Add(f.Timeout)
+	}
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return appErr.NewInternalError("encode flow "+f.Name, err)
+	}
+	return m.Set(ctx, userID, NewState(f.Type(), map[string]interface{}{"flow": string(raw)}))
+}
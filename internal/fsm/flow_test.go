@@ -0,0 +1,158 @@
+package fsm
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type sourceData struct {
+	URL      string   `json:"url"`
+	Tags     []string `json:"tags"`
+	Schedule bool     `json:"schedule"`
+	Hour     int      `json:"hour"`
+}
+
+func sourceFlow(done *sourceData) *Flow[sourceData] {
+	return &Flow[sourceData]{
+		Name:    "add_source",
+		Timeout: time.Hour,
+		Steps: []Step[sourceData]{
+			{
+				Name:   "url",
+				Prompt: func(*sourceData) string { return "url?" },
+				Parse: func(_ context.Context, d *sourceData, in string) error {
+					if !strings.HasPrefix(in, "https://") {
+						return InputError("bad url")
+					}
+					d.URL = in
+					return nil
+				},
+			},
+			{
+				Name:   "tags",
+				Prompt: func(*sourceData) string { return "tags?" },
+				Parse: func(_ context.Context, d *sourceData, in string) error {
+					d.Tags = strings.Fields(in)
+					return nil
+				},
+			},
+			{
+				Name:   "schedule",
+				Prompt: func(*sourceData) string { return "schedule?" },
+				Parse: func(_ context.Context, d *sourceData, in string) error {
+					d.Schedule = in == "yes"
+					return nil
+				},
+				Next: func(d *sourceData) string {
+					if !d.Schedule {
+						return Finish
+					}
+					return ""
+				},
+			},
+			{
+				Name:   "hour",
+				Prompt: func(*sourceData) string { return "hour?" },
+				Parse: func(_ context.Context, d *sourceData, in string) error {
+					h, err := strconv.Atoi(in)
+					if err != nil || h < 0 || h > 23 {
+						return InputError("0-23")
+					}
+					d.Hour = h
+					return nil
+				},
+			},
+		},
+		Finish: func(_ context.Context, _ int, d *sourceData) (string, error) {
+			*done = *d
+			return "done", nil
+		},
+	}
+}
+
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+	m := NewManager(NewMemoryStore(time.Hour), time.Hour)
+	t.Cleanup(m.Shutdown)
+	return m
+}
+
+func step(t *testing.T, f Runner, m *Manager, input, want string) {
+	t.Helper()
+	got, err := f.Handle(context.Background(), m, 1, input)
+	if err != nil {
+		t.Fatalf("%q: %v", input, err)
+	}
+	if got != want {
+		t.Fatalf("%q: reply %q, want %q", input, got, want)
+	}
+}
+
+func TestFlowStepsValidationAndBack(t *testing.T) {
+	var done sourceData
+	f := sourceFlow(&done)
+	m := newTestManager(t)
+	if p, err := f.Start(context.Background(), m, 1, sourceData{}); err != nil || p != "url?" {
+		t.Fatalf("start: %q %v", p, err)
+	}
+	step(t, f, m, "ftp://x", "bad url\n\nurl?")
+	step(t, f, m, "https://a", "tags?")
+	step(t, f, m, BackCommand, "url?")
+	step(t, f, m, "https://b", "tags?")
+	step(t, f, m, "x y", "schedule?")
+	step(t, f, m, "yes", "hour?")
+	step(t, f, m, "25", "0-23\n\nhour?")
+	step(t, f, m, "12", "done")
+
+	if done.URL != "https://b" || len(done.Tags) != 2 || !done.Schedule || done.Hour != 12 {
+		t.Fatalf("data = %+v", done)
+	}
+	if st, _ := m.Get(context.Background(), 1); st.Type != StateDefault {
+		t.Fatalf("state after finish = %s", st.Type)
+	}
+}
+
+func TestFlowNextFinishesEarly(t *testing.T) {
+	var done sourceData
+	f := sourceFlow(&done)
+	m := newTestManager(t)
+	_, _ = f.Start(context.Background(), m, 1, sourceData{})
+	step(t, f, m, "https://a", "tags?")
+	step(t, f, m, "x", "schedule?")
+	step(t, f, m, "no", "done")
+	if done.Schedule || done.Hour != 0 {
+		t.Fatalf("data = %+v", done)
+	}
+}
+
+func TestFlowWithoutFinishRejected(t *testing.T) {
+	f := sourceFlow(new(sourceData))
+	f.Finish = nil
+	m := newTestManager(t)
+	if _, err := f.Start(context.Background(), m, 1, sourceData{}); err == nil {
+		t.Fatal("flow without Finish started")
+	}
+	// состояние, сохранённое до того, как Finish убрали, не должно ронять бота
+	_ = m.Set(context.Background(), 1, NewState(f.Type(), map[string]interface{}{"flow": `{"step":"hour"}`}))
+	if _, err := f.Handle(context.Background(), m, 1, "12"); err == nil {
+		t.Fatal("flow without Finish handled the last step")
+	}
+}
+
+func TestFlowCancelAndTimeout(t *testing.T) {
+	f := sourceFlow(new(sourceData))
+	m := newTestManager(t)
+	_, _ = f.Start(context.Background(), m, 1, sourceData{})
+	step(t, f, m, CancelCommand, "Отменено")
+	if _, err := f.Handle(context.Background(), m, 1, "https://a"); err != ErrNoFlow {
+		t.Fatalf("after cancel: %v", err)
+	}
+
+	f.Timeout = time.Nanosecond
+	_, _ = f.Start(context.Background(), m, 1, sourceData{})
+	time.Sleep(time.Millisecond)
+	step(t, f, m, "https://a", "Время ожидания ответа истекло, начните заново.")
+}
@@ -1,7 +1,7 @@
 package fsm
 
 import (
-	"sync"
+	"context"
 	"time"
 
 	"go_scripts/internal/logger"
@@ -29,48 +29,36 @@ func NewState(t StateType, data map[string]interface{}) State {
 func Start() State     { return NewState(StateDefault, nil) }
 func AwaitLink() State { return NewState(StateAwaitLink, nil) }
 
-type UserStateEntry struct {
-	State     State
-	LastSeen  time.Time
-	CreatedAt time.Time
-}
-
+// Manager — состояния пользователей поверх Store с периодической очисткой
+// истёкших.
 type Manager struct {
-	states   map[int]*UserStateEntry
-	mutex    sync.RWMutex
-	ttl      time.Duration
+	store    Store
 	cleanup  time.Duration
 	stopChan chan struct{}
 }
 
-func NewManager(ttl, cleanupInterval time.Duration) *Manager {
-	m := &Manager{states: map[int]*UserStateEntry{}, ttl: ttl, cleanup: cleanupInterval, stopChan: make(chan struct{})}
+func NewManager(store Store, cleanupInterval time.Duration) *Manager {
+	m := &Manager{store: store, cleanup: cleanupInterval, stopChan: make(chan struct{})}
 	go m.cleanupLoop()
 	return m
 }
 
-func (m *Manager) Get(userID int) (State, bool) {
-	m.mutex.RLock()
-	defer m.mutex.RUnlock()
-	e, ok := m.states[userID]
-	if !ok {
+// Get возвращает состояние пользователя; при ошибке хранилища — как отсутствующее.
+func (m *Manager) Get(ctx context.Context, userID int) (State, bool) {
+	s, ok, err := m.store.Get(ctx, userID)
+	if err != nil {
+		logger.BotError("get state user=%d: %v", userID, err)
 		return State{}, false
 	}
-	e.LastSeen = time.Now()
-	return e.State, true
+	return s, ok
 }
 
-func (m *Manager) Set(userID int, s State) {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
-	now := time.Now()
-	if e, ok := m.states[userID]; ok {
-		e.State = s
-		e.LastSeen = now
-	} else {
-		m.states[userID] = &UserStateEntry{State: s, LastSeen: now, CreatedAt: now}
-		logger.UserInfo(userID, "Новый пользователь")
+func (m *Manager) Set(ctx context.Context, userID int, s State) error {
+	if err := m.store.Set(ctx, userID, s); err != nil {
+		logger.BotError("set state user=%d: %v", userID, err)
+		return err
 	}
+	return nil
 }
 
 func (m *Manager) cleanupLoop() {
@@ -87,15 +75,10 @@ func (m *Manager) cleanupLoop() {
 }
 
 func (m *Manager) cleanupExpiredStates() {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
-	now := time.Now()
-	expired := 0
-	for uid, e := range m.states {
-		if now.Sub(e.LastSeen) > m.ttl {
-			delete(m.states, uid)
-			expired++
-		}
+	expired, err := m.store.Cleanup(context.Background())
+	if err != nil {
+		logger.BotError("cleanup states: %v", err)
+		return
 	}
 	if expired > 0 {
 		logger.BotInfo("Очищено %d устаревших состояний пользователей", expired)
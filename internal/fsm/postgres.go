@@ -0,0 +1,92 @@
+package fsm
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	appErr "go_scripts/internal/errors"
+)
+
+// userStateRow — строка таблицы user_states. После ExpiresAt состояние
+// считается отсутствующим.
+type userStateRow struct {
+	UserID    int64     `gorm:"primaryKey;autoIncrement:false"`
+	Type      string    `gorm:"type:varchar(64);not null"`
+	DataJSON  string    `gorm:"type:text"`
+	ExpiresAt time.Time `gorm:"index;not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (userStateRow) TableName() string { return "user_states" }
+
+// PostgresStore — состояния в таблице user_states: диалог продолжается после
+// перезапуска бота. State.Data хранится как JSON, поэтому числа после
+// загрузки — float64.
+type PostgresStore struct {
+	db  *gorm.DB
+	ttl time.Duration
+}
+
+// NewPostgresStore создаёт хранилище поверх db и при необходимости создаёт
+// таблицу user_states.
+func NewPostgresStore(db *gorm.DB, ttl time.Duration) (*PostgresStore, error) {
+	if err := db.AutoMigrate(&userStateRow{}); err != nil {
+		return nil, appErr.NewDatabaseError("migrate user_states", err)
+	}
+	return &PostgresStore{db: db, ttl: ttl}, nil
+}
+
+// Get возвращает неистёкшее состояние и продлевает его на ttl одним запросом.
+func (p *PostgresStore) Get(ctx context.Context, userID int) (State, bool, error) {
+	var rows []userStateRow
+	err := p.db.WithContext(ctx).Model(&rows).Clauses(clause.Returning{}).
+		Where("user_id = ? AND expires_at > NOW()", userID).
+		Updates(map[string]any{"expires_at": time.Now().Add(p.ttl), "updated_at": time.Now()}).Error
+	if err != nil {
+		return State{}, false, appErr.NewDatabaseError("load user state", err)
+	}
+	if len(rows) == 0 {
+		return State{}, false, nil
+	}
+	s := NewState(StateType(rows[0].Type), nil)
+	if rows[0].DataJSON != "" {
+		if err := json.Unmarshal([]byte(rows[0].DataJSON), &s.Data); err != nil {
+			return State{}, false, appErr.NewInternalError("decode user state", err)
+		}
+	}
+	return s, true, nil
+}
+
+// Set создаёт или заменяет состояние пользователя.
+func (p *PostgresStore) Set(ctx context.Context, userID int, s State) error {
+	var data string
+	if len(s.Data) > 0 {
+		b, err := json.Marshal(s.Data)
+		if err != nil {
+			return appErr.NewInternalError("encode user state", err)
+		}
+		data = string(b)
+	}
+	row := &userStateRow{UserID: int64(userID), Type: string(s.Type), DataJSON: data, ExpiresAt: time.Now().Add(p.ttl)}
+	err := p.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"type", "data_json", "expires_at", "updated_at"}),
+	}).Create(row).Error
+	if err != nil {
+		return appErr.NewDatabaseError("save user state", err)
+	}
+	return nil
+}
+
+func (p *PostgresStore) Cleanup(ctx context.Context) (int, error) {
+	res := p.db.WithContext(ctx).Where("expires_at <= NOW()").Delete(&userStateRow{})
+	if res.Error != nil {
+		return 0, appErr.NewDatabaseError("delete expired user states", res.Error)
+	}
+	return int(res.RowsAffected), nil
+}
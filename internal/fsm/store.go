@@ -0,0 +1,75 @@
+package fsm
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go_scripts/internal/logger"
+)
+
+// Store хранит состояния пользователей. Состояние, к которому не обращались
+// дольше TTL хранилища, считается отсутствующим.
+type Store interface {
+	// Get возвращает состояние и продлевает его срок.
+	Get(ctx context.Context, userID int) (State, bool, error)
+	Set(ctx context.Context, userID int, s State) error
+	// Cleanup удаляет истёкшие состояния и возвращает их число.
+	Cleanup(ctx context.Context) (int, error)
+}
+
+type UserStateEntry struct {
+	State     State
+	LastSeen  time.Time
+	CreatedAt time.Time
+}
+
+// MemoryStore — состояния в памяти процесса; теряются при перезапуске.
+type MemoryStore struct {
+	states map[int]*UserStateEntry
+	mutex  sync.Mutex
+	ttl    time.Duration
+}
+
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{states: map[int]*UserStateEntry{}, ttl: ttl}
+}
+
+func (m *MemoryStore) Get(_ context.Context, userID int) (State, bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	e, ok := m.states[userID]
+	if !ok || time.Since(e.LastSeen) > m.ttl {
+		return State{}, false, nil
+	}
+	e.LastSeen = time.Now()
+	return e.State, true, nil
+}
+
+func (m *MemoryStore) Set(_ context.Context, userID int, s State) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	now := time.Now()
+	if e, ok := m.states[userID]; ok {
+		e.State = s
+		e.LastSeen = now
+	} else {
+		m.states[userID] = &UserStateEntry{State: s, LastSeen: now, CreatedAt: now}
+		logger.UserInfo(userID, "Новый пользователь")
+	}
+	return nil
+}
+
+func (m *MemoryStore) Cleanup(context.Context) (int, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	now := time.Now()
+	expired := 0
+	for uid, e := range m.states {
+		if now.Sub(e.LastSeen) > m.ttl {
+			delete(m.states, uid)
+			expired++
+		}
+	}
+	return expired, nil
+}
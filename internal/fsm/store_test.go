@@ -0,0 +1,102 @@
+package fsm
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testUser — пользователь, которого нет в рабочих данных: тесты PostgresStore
+// могут идти на общей базе.
+const testUser = -424242
+
+// stores перечисляет реализации Store; PostgresStore проверяется только при
+// заданном TEST_POSTGRES_DSN.
+var stores = []struct {
+	name string
+	open func(t *testing.T, ttl time.Duration) Store
+}{
+	{"memory", func(_ *testing.T, ttl time.Duration) Store { return NewMemoryStore(ttl) }},
+	{"postgres", func(t *testing.T, ttl time.Duration) Store {
+		dsn := os.Getenv("TEST_POSTGRES_DSN")
+		if dsn == "" {
+			t.Skip("TEST_POSTGRES_DSN не задан")
+		}
+		db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, err := NewPostgresStore(db, ttl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		clean := func() { db.Where("user_id = ?", testUser).Delete(&userStateRow{}) }
+		clean()
+		t.Cleanup(clean)
+		return s
+	}},
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	for _, tc := range stores {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := tc.open(t, time.Hour)
+
+			if _, ok, err := s.Get(ctx, testUser); err != nil || ok {
+				t.Fatalf("missing user: ok=%v err=%v", ok, err)
+			}
+			if err := s.Set(ctx, testUser, AwaitLink()); err != nil {
+				t.Fatal(err)
+			}
+			st, ok, err := s.Get(ctx, testUser)
+			if err != nil || !ok || st.Type != StateAwaitLink {
+				t.Fatalf("got %v %v %v", st, ok, err)
+			}
+
+			want := NewState("await_title", map[string]interface{}{"url": "https://example.com/g/1"})
+			if err := s.Set(ctx, testUser, want); err != nil {
+				t.Fatal(err)
+			}
+			st, ok, err = s.Get(ctx, testUser)
+			if err != nil || !ok || st.Type != want.Type || st.Data["url"] != want.Data["url"] {
+				t.Fatalf("after overwrite got %v %v %v", st, ok, err)
+			}
+		})
+	}
+}
+
+func TestStoreExpires(t *testing.T) {
+	const ttl = 300 * time.Millisecond
+	for _, tc := range stores {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := tc.open(t, ttl)
+			if err := s.Set(ctx, testUser, AwaitLink()); err != nil {
+				t.Fatal(err)
+			}
+
+			// Get продлевает срок: после двух обращений с шагом ttl/2 состояние живо
+			for i := 0; i < 2; i++ {
+				time.Sleep(ttl / 2)
+				if _, ok, err := s.Get(ctx, testUser); err != nil || !ok {
+					t.Fatalf("touch %d: ok=%v err=%v", i, ok, err)
+				}
+			}
+
+			time.Sleep(ttl + 100*time.Millisecond)
+			if _, ok, err := s.Get(ctx, testUser); err != nil || ok {
+				t.Fatalf("expired state: ok=%v err=%v", ok, err)
+			}
+			n, err := s.Cleanup(ctx)
+			if err != nil || n < 1 {
+				t.Fatalf("cleanup removed %d (%v), want at least 1", n, err)
+			}
+		})
+	}
+}
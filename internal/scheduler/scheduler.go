@@ -2,18 +2,21 @@ package scheduler
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
 
 	"go_scripts/database"
+	appErr "go_scripts/internal/errors"
 	"go_scripts/internal/logger"
 	"go_scripts/internal/telegram"
 )
 
+// actor is recorded in content_events for transitions made by the scheduler
+const actor = "scheduler"
+
 type Runner struct {
-	BotURL       string
+	Client       *telegram.Client
 	ChannelID    int64
 	IntervalSec  int
 	SubscribeURL string
@@ -44,7 +47,7 @@ func (r *Runner) Run(ctx context.Context) {
 			} else {
 				for _, item := range parsed {
 					if item.UrlTelegraph == "" {
-						_ = database.ContentMarkError(item.ID, "empty telegraph url")
+						_ = database.ContentMarkError(item.ID, "empty telegraph url", actor)
 						continue
 					}
 					text := r.buildMessageText(item)
@@ -53,7 +56,7 @@ func (r *Runner) Run(ctx context.Context) {
 						{Text: "Отклонить", CallbackData: fmt.Sprintf("reject:%d", item.ID)},
 					}}}
 					for _, adm := range admins {
-						_ = telegram.SendMessageWithPreviewAndKeyboard(r.BotURL, adm.TelegramUserID, text, item.UrlTelegraph, true, false, markup)
+						_, _ = r.Client.SendWithPreview(ctx, adm.TelegramUserID, text, item.UrlTelegraph, true, false, markup)
 					}
 					_ = database.ContentMarkReviewSent(item.ID)
 				}
@@ -68,18 +71,40 @@ func (r *Runner) Run(ctx context.Context) {
 		}
 		for _, item := range due {
 			if item.UrlTelegraph == "" {
-				_ = database.ContentMarkError(item.ID, "empty telegraph url")
+				_ = database.ContentMarkError(item.ID, "empty telegraph url", actor)
 				continue
 			}
 			// Build message text with meta fields
 			text := r.buildMessageText(item)
 			// Send message with large preview shown below text
-			_ = telegram.SendMessageWithPreview(r.BotURL, r.ChannelID, text, item.UrlTelegraph, true, false)
-			_ = database.ContentMarkSent(item.ID)
+			msg, err := r.post(ctx, text, item.UrlTelegraph)
+			if err != nil {
+				if appErr.IsTransient(err) {
+					// stays Confirmed and is picked up again on the next tick
+					logger.Warn("BOT", "post content %d: %v, retrying later", item.ID, err)
+					continue
+				}
+				_ = database.ContentMarkError(item.ID, err.Error(), actor)
+				continue
+			}
+			logger.Info("BOT", "content %d posted as message %d", item.ID, msg.MessageID)
+			_ = database.ContentMarkSent(item.ID, actor)
 		}
 	}
 }
 
+// post sends a channel post; if the group was upgraded to a supergroup,
+// it switches ChannelID to the new id and sends again.
+func (r *Runner) post(ctx context.Context, text, previewURL string) (*telegram.Message, error) {
+	msg, err := r.Client.SendWithPreview(ctx, r.ChannelID, text, previewURL, true, false, nil)
+	if newID, ok := telegram.MigrateToChatID(err); ok {
+		logger.Warn("BOT", "chat %d migrated to %d, update TELEGRAM_CHANNEL_ID", r.ChannelID, newID)
+		r.ChannelID = newID
+		msg, err = r.Client.SendWithPreview(ctx, r.ChannelID, text, previewURL, true, false, nil)
+	}
+	return msg, err
+}
+
 func (r *Runner) buildMessageText(item database.Content) string {
 	// Compose message using HTML formatting (safer around entities)
 	b := strings.Builder{}
@@ -95,18 +120,18 @@ func (r *Runner) buildMessageText(item database.Content) string {
 		b.WriteString(escapeHTML(item.Series))
 		b.WriteString("\n")
 	}
-	if item.Author != "" {
+	if authors := item.Authors(); len(authors) > 0 {
 		b.WriteString("<b>Автор:</b> ")
-		b.WriteString(escapeHTML(item.Author))
+		b.WriteString(escapeHTML(strings.Join(authors, ", ")))
 		b.WriteString("\n")
 	}
-	if item.Translator != "" {
+	if translators := item.Translators(); len(translators) > 0 {
 		b.WriteString("<b>Переводчик:</b> ")
-		b.WriteString(escapeHTML(item.Translator))
+		b.WriteString(escapeHTML(strings.Join(translators, ", ")))
 		b.WriteString("\n")
 	}
 	if item.TagsJSON != "" {
-		if tags := parseTags(item.TagsJSON); len(tags) > 0 {
+		if tags := item.Tags(); len(tags) > 0 {
 			b.WriteString("<b>Теги:</b> ")
 			// prefix each tag with an escaped '#', replacing spaces with underscores
 			for i, t := range tags {
@@ -130,12 +155,6 @@ func (r *Runner) buildMessageText(item database.Content) string {
 	return b.String()
 }
 
-func parseTags(tagsJSON string) []string {
-	var tags []string
-	_ = json.Unmarshal([]byte(tagsJSON), &tags)
-	return tags
-}
-
 // basic HTML escaping
 func escapeHTML(s string) string {
 	s = strings.ReplaceAll(s, "&", "&amp;")
@@ -0,0 +1,193 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	appErr "go_scripts/internal/errors"
+	"go_scripts/internal/logger"
+)
+
+const DefaultAPIURL = "https://api.telegram.org/bot"
+
+// Config — настройки клиента Bot API.
+type Config struct {
+	APIURL     string // по умолчанию DefaultAPIURL, токен дописывается в конец
+	Token      string
+	Timeout    time.Duration // таймаут обычного запроса; getUpdates ждёт дольше на время long polling
+	HTTPClient *http.Client  // nil — http.Client без общего таймаута
+	Limits     *RateLimits   // nil — DefaultRateLimits
+}
+
+// Client — клиент Telegram Bot API.
+type Client struct {
+	botURL  string
+	timeout time.Duration
+	http    *http.Client
+	limiter *sendLimiter
+}
+
+func NewClient(c Config) *Client {
+	if c.APIURL == "" {
+		c.APIURL = DefaultAPIURL
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 30 * time.Second
+	}
+	if c.HTTPClient == nil {
+		c.HTTPClient = &http.Client{}
+	}
+	limits := DefaultRateLimits()
+	if c.Limits != nil {
+		limits = *c.Limits
+	}
+	return &Client{
+		botURL:  strings.TrimRight(c.APIURL, "/") + c.Token,
+		timeout: c.Timeout,
+		http:    c.HTTPClient,
+		limiter: newSendLimiter(limits),
+	}
+}
+
+// response — общий конверт ответа Bot API.
+type response struct {
+	Ok          bool                `json:"ok"`
+	Result      json.RawMessage     `json:"result"`
+	ErrorCode   int                 `json:"error_code"`
+	Description string              `json:"description"`
+	Parameters  *ResponseParameters `json:"parameters"`
+}
+
+// ResponseParameters — подсказки Bot API, как повторить неудачный запрос.
+type ResponseParameters struct {
+	MigrateToChatID int64 `json:"migrate_to_chat_id,omitempty"`
+	RetryAfter      int   `json:"retry_after,omitempty"`
+}
+
+// APIError — ошибка, которую вернул Bot API (ok=false). Приходит внутри
+// AppError типа telegram; достаётся через AsAPIError.
+type APIError struct {
+	Method          string
+	Code            int // error_code: 400, 403, 429, ...
+	Description     string
+	RetryAfter      time.Duration // 429: пауза перед повтором
+	MigrateToChatID int64         // группа стала супергруппой с новым id
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s: %d %s", e.Method, e.Code, e.Description)
+}
+
+// AsAPIError достаёт APIError из цепочки ошибок.
+func AsAPIError(err error) (*APIError, bool) {
+	var e *APIError
+	ok := stderrors.As(err, &e)
+	return e, ok
+}
+
+// MigrateToChatID возвращает новый id чата, если err сообщает о миграции группы.
+func MigrateToChatID(err error) (int64, bool) {
+	if e, ok := AsAPIError(err); ok && e.MigrateToChatID != 0 {
+		return e.MigrateToChatID, true
+	}
+	return 0, false
+}
+
+// Call вызывает метод Bot API с параметрами params (сериализуются в JSON)
+// и раскладывает result в out. Ошибки — AppError: сетевые и 5xx/429 временные
+// (429 с RetryAfter), остальные ответы API — постоянные с кодом error_code.
+func (c *Client) Call(ctx context.Context, method string, params, out any) error {
+	return c.call(ctx, method, params, out, c.timeout)
+}
+
+// callChat — Call для методов, отправляющих что-то в чат chatID: ждёт
+// очереди в лимитере и при 429 повторяет запрос после retry_after (пока
+// он не длиннее MaxRetryAfter и не исчерпаны MaxRetries).
+func (c *Client) callChat(ctx context.Context, chatID int64, method string, params, out any) error {
+	for attempt := 0; ; attempt++ {
+		if err := c.limiter.acquire(ctx, chatID); err != nil {
+			return err
+		}
+		err := c.Call(ctx, method, params, out)
+		e, ok := AsAPIError(err)
+		if !ok || e.Code != http.StatusTooManyRequests {
+			return err
+		}
+		wait := e.RetryAfter
+		if wait <= 0 {
+			wait = time.Second
+		}
+		c.limiter.penalize(chatID, wait)
+		opts := c.limiter.opts
+		if attempt >= opts.MaxRetries || (opts.MaxRetryAfter > 0 && wait > opts.MaxRetryAfter) {
+			return err
+		}
+		logger.TelegramWarn("%s: 429 в чате %d, повтор через %s", method, chatID, wait)
+	}
+}
+
+func (c *Client) call(ctx context.Context, method string, params, out any, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if params == nil {
+		params = struct{}{}
+	}
+	body, err := json.Marshal(params)
+	if err != nil {
+		return appErr.NewInternalError("telegram "+method+": marshal", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.botURL+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return appErr.NewInternalError("telegram "+method, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return appErr.NewNetworkError("Ошибка запроса "+method, err).WithTransient(true)
+	}
+	defer resp.Body.Close()
+
+	var r response
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		if resp.StatusCode >= 500 {
+			return appErr.NewNetworkError("Ошибка сервера Telegram", fmt.Errorf("%s: %s", method, resp.Status)).WithTransient(true)
+		}
+		return appErr.NewTelegramError("Ошибка парсинга ответа "+method, err)
+	}
+	if !r.Ok {
+		return apiError(method, &r)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(r.Result, out); err != nil {
+		return appErr.NewTelegramError("Ошибка парсинга result "+method, err)
+	}
+	return nil
+}
+
+func apiError(method string, r *response) *appErr.AppError {
+	e := &APIError{Method: method, Code: r.ErrorCode, Description: r.Description}
+	if p := r.Parameters; p != nil {
+		e.RetryAfter = time.Duration(p.RetryAfter) * time.Second
+		e.MigrateToChatID = p.MigrateToChatID
+	}
+	ae := appErr.NewTelegramError("Ошибка API Telegram", e).WithCode(strconv.Itoa(e.Code))
+	switch {
+	case e.Code == http.StatusTooManyRequests:
+		ae.WithRetryAfter(e.RetryAfter)
+	case e.Code >= 500:
+		ae.WithTransient(true)
+	}
+	if e.MigrateToChatID != 0 {
+		ae.WithContext("migrate_to_chat_id", e.MigrateToChatID)
+	}
+	return ae
+}
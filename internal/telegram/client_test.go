@@ -0,0 +1,137 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	appErr "go_scripts/internal/errors"
+)
+
+// newTestClient поднимает сервер, который на любой метод отвечает reply,
+// и запоминает путь и тело последнего запроса.
+func newTestClient(t *testing.T, reply string) (*Client, *string, *map[string]any) {
+	t.Helper()
+	return newTestClientLimits(t, reply, &RateLimits{})
+}
+
+func newTestClientLimits(t *testing.T, reply string, limits *RateLimits) (*Client, *string, *map[string]any) {
+	t.Helper()
+	var path string
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		body = nil
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		_, _ = w.Write([]byte(reply))
+	}))
+	t.Cleanup(srv.Close)
+	return NewClient(Config{APIURL: srv.URL + "/bot", Token: "tok", Timeout: 5 * time.Second, Limits: limits}), &path, &body
+}
+
+func TestSendMessageReturnsMessage(t *testing.T) {
+	c, path, body := newTestClient(t, `{"ok":true,"result":{"message_id":42,"chat":{"id":-100,"type":"channel"},"date":1,"text":"hi"}}`)
+	m, err := c.Send(context.Background(), -100, "hi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.MessageID != 42 || m.Chat.ID != -100 {
+		t.Fatalf("message = %+v", m)
+	}
+	if *path != "/bottok/sendMessage" {
+		t.Fatalf("path = %s", *path)
+	}
+	if (*body)["parse_mode"] != "HTML" || (*body)["text"] != "hi" {
+		t.Fatalf("body = %v", *body)
+	}
+}
+
+func TestCallTooManyRequests(t *testing.T) {
+	c, _, _ := newTestClient(t, `{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 7","parameters":{"retry_after":7}}`)
+	_, err := c.Send(context.Background(), 1, "x")
+	if !appErr.IsTransient(err) || appErr.RetryAfterOf(err) != 7*time.Second {
+		t.Fatalf("want transient with retry_after 7s, got %v", err)
+	}
+	e, ok := AsAPIError(err)
+	if !ok || e.Code != 429 || e.Method != "sendMessage" {
+		t.Fatalf("api error = %+v", e)
+	}
+}
+
+func TestCallServerErrorIsTransient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}))
+	t.Cleanup(srv.Close)
+	c := NewClient(Config{APIURL: srv.URL + "/bot", Token: "tok", Limits: &RateLimits{}})
+	if _, err := c.Send(context.Background(), 1, "x"); !appErr.IsTransient(err) {
+		t.Fatalf("5xx must be transient: %v", err)
+	}
+	srv.Close()
+	if _, err := c.Send(context.Background(), 1, "x"); !appErr.IsTransient(err) {
+		t.Fatalf("network error must be transient: %v", err)
+	}
+}
+
+func TestCallMigrateToChat(t *testing.T) {
+	c, _, _ := newTestClient(t, `{"ok":false,"error_code":400,"description":"Bad Request: group chat was upgraded to a supergroup chat","parameters":{"migrate_to_chat_id":-1001234}}`)
+	_, err := c.Send(context.Background(), -55, "x")
+	if appErr.IsTransient(err) {
+		t.Fatalf("400 must not be transient: %v", err)
+	}
+	if id, ok := MigrateToChatID(err); !ok || id != -1001234 {
+		t.Fatalf("migrate_to_chat_id = %d %v", id, ok)
+	}
+}
+
+func TestGetUpdatesSendsOffsetAndTimeout(t *testing.T) {
+	c, path, body := newTestClient(t, `{"ok":true,"result":[{"update_id":5,"message":{"message_id":1,"chat":{"id":9,"type":"private"},"date":1,"text":"/start"}}]}`)
+	updates, err := c.GetUpdates(context.Background(), 5, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updates) != 1 || updates[0].Message.Text != "/start" {
+		t.Fatalf("updates = %+v", updates)
+	}
+	if *path != "/bottok/getUpdates" || (*body)["offset"] != float64(5) {
+		t.Fatalf("path = %s, body = %v", *path, *body)
+	}
+}
+
+func TestSendRetriesAfterTooManyRequests(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			_, _ = w.Write([]byte(`{"ok":false,"error_code":429,"description":"Too Many Requests","parameters":{"retry_after":1}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":7,"chat":{"id":1,"type":"private"},"date":1}}`))
+	}))
+	t.Cleanup(srv.Close)
+	c := NewClient(Config{APIURL: srv.URL + "/bot", Token: "tok", Limits: &RateLimits{MaxRetries: 2, MaxRetryAfter: time.Minute}})
+
+	start := time.Now()
+	m, err := c.Send(context.Background(), 1, "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.MessageID != 7 || calls != 2 {
+		t.Fatalf("message = %+v, calls = %d", m, calls)
+	}
+	if d := time.Since(start); d < time.Second {
+		t.Fatalf("retried after %s, want >= retry_after", d)
+	}
+}
+
+func TestSendGivesUpOnLongRetryAfter(t *testing.T) {
+	c, _, _ := newTestClientLimits(t, `{"ok":false,"error_code":429,"description":"Too Many Requests","parameters":{"retry_after":600}}`,
+		&RateLimits{MaxRetries: 3, MaxRetryAfter: time.Minute})
+	_, err := c.Send(context.Background(), 1, "x")
+	if appErr.RetryAfterOf(err) != 600*time.Second {
+		t.Fatalf("want error with retry_after 600s, got %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+package telegram
+
+import "context"
+
+// BotCommand — команда в меню бота.
+type BotCommand struct {
+	Command     string `json:"command"`
+	Description string `json:"description"`
+}
+
+// BotCommandScope — кому показывать список команд: "default" — всем,
+// "chat" — одному чату (ChatID).
+type BotCommandScope struct {
+	Type   string `json:"type"`
+	ChatID int64  `json:"chat_id,omitempty"`
+}
+
+type setMyCommandsParams struct {
+	Commands []BotCommand    `json:"commands"`
+	Scope    BotCommandScope `json:"scope"`
+}
+
+// SetMyCommands заменяет меню команд бота для scope.
+func (c *Client) SetMyCommands(ctx context.Context, commands []BotCommand, scope BotCommandScope) error {
+	return c.Call(ctx, "setMyCommands", setMyCommandsParams{Commands: commands, Scope: scope}, nil)
+}
+
+// GetMe возвращает пользователя-бота (нужен username для команд вида /cmd@bot).
+func (c *Client) GetMe(ctx context.Context) (*User, error) {
+	var u User
+	if err := c.Call(ctx, "getMe", nil, &u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
@@ -0,0 +1,133 @@
+package telegram
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimits — ограничения исходящих сообщений по правилам Bot API: не больше
+// ~30 сообщений в секунду всего, ~1 в секунду в один личный чат и ~20 в минуту
+// в группу или канал.
+type RateLimits struct {
+	GlobalPerSec  int           // сообщений в секунду по всем чатам; <= 0 — без ограничения
+	ChatInterval  time.Duration // пауза между сообщениями в один личный чат
+	GroupPerMin   int           // сообщений в минуту в группу или канал; <= 0 — без ограничения
+	MaxRetries    int           // повторов после 429
+	MaxRetryAfter time.Duration // retry_after больше этого не ждём, а возвращаем ошибку
+}
+
+func DefaultRateLimits() RateLimits {
+	return RateLimits{
+		GlobalPerSec:  30,
+		ChatInterval:  time.Second,
+		GroupPerMin:   20,
+		MaxRetries:    3,
+		MaxRetryAfter: 5 * time.Minute,
+	}
+}
+
+// sendLimiter — общая очередь исходящих сообщений клиента. Каждый чат
+// получает слоты по порядку вызовов (следующий слот чата — не раньше
+// предыдущего плюс интервал), а поверх — общий token bucket на все чаты.
+type sendLimiter struct {
+	opts   RateLimits
+	mu     sync.Mutex
+	chats  map[int64]time.Time // ближайший свободный слот чата
+	tokens float64
+	last   time.Time
+}
+
+func newSendLimiter(o RateLimits) *sendLimiter {
+	return &sendLimiter{opts: o, chats: map[int64]time.Time{}, tokens: float64(max(o.GlobalPerSec, 1)), last: time.Now()}
+}
+
+// acquire ждёт очереди на отправку в chatID.
+func (l *sendLimiter) acquire(ctx context.Context, chatID int64) error {
+	if err := sleepCtx(ctx, l.reserveChat(chatID, time.Now())); err != nil {
+		return err
+	}
+	for {
+		wait := l.reserveGlobal(time.Now())
+		if wait <= 0 {
+			return nil
+		}
+		if err := sleepCtx(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// reserveChat занимает следующий слот чата и возвращает, сколько до него ждать.
+func (l *sendLimiter) reserveChat(chatID int64, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.chats) > 1024 {
+		for id, t := range l.chats {
+			if t.Before(now) {
+				delete(l.chats, id)
+			}
+		}
+	}
+	slot := now
+	if t := l.chats[chatID]; t.After(slot) {
+		slot = t
+	}
+	l.chats[chatID] = slot.Add(l.interval(chatID))
+	return slot.Sub(now)
+}
+
+// interval — минимальная пауза между сообщениями в чат. Отрицательные id —
+// группы и каналы.
+func (l *sendLimiter) interval(chatID int64) time.Duration {
+	if chatID < 0 {
+		if l.opts.GroupPerMin <= 0 {
+			return 0
+		}
+		return time.Minute / time.Duration(l.opts.GroupPerMin)
+	}
+	return l.opts.ChatInterval
+}
+
+// reserveGlobal забирает токен общего лимита или возвращает, сколько ждать.
+func (l *sendLimiter) reserveGlobal(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	rate := float64(l.opts.GlobalPerSec)
+	if rate <= 0 {
+		return 0
+	}
+	l.tokens += now.Sub(l.last).Seconds() * rate
+	if l.tokens > rate {
+		l.tokens = rate
+	}
+	l.last = now
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	return time.Duration((1 - l.tokens) / rate * float64(time.Second))
+}
+
+// penalize откладывает все сообщения в чат на d (retry_after из ответа 429).
+func (l *sendLimiter) penalize(chatID int64, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := time.Now().Add(d); until.After(l.chats[chatID]) {
+		l.chats[chatID] = until
+	}
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,46 @@
+package telegram
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReserveChatSpacesMessages(t *testing.T) {
+	l := newSendLimiter(RateLimits{ChatInterval: time.Second, GroupPerMin: 20})
+	now := time.Now()
+	if d := l.reserveChat(1, now); d != 0 {
+		t.Fatalf("first message waits %s", d)
+	}
+	if d := l.reserveChat(1, now); d != time.Second {
+		t.Fatalf("second private message waits %s, want 1s", d)
+	}
+	if d := l.reserveChat(2, now); d != 0 {
+		t.Fatalf("other chat waits %s", d)
+	}
+	l.reserveChat(-100, now)
+	if d := l.reserveChat(-100, now); d != 3*time.Second {
+		t.Fatalf("group message waits %s, want 3s", d)
+	}
+}
+
+func TestPenalizeDelaysChat(t *testing.T) {
+	l := newSendLimiter(RateLimits{})
+	l.penalize(5, time.Minute)
+	if d := l.reserveChat(5, time.Now()); d < 59*time.Second {
+		t.Fatalf("penalized chat waits %s", d)
+	}
+	if d := l.reserveChat(6, time.Now()); d != 0 {
+		t.Fatalf("other chat waits %s", d)
+	}
+}
+
+func TestReserveGlobalBucket(t *testing.T) {
+	l := newSendLimiter(RateLimits{GlobalPerSec: 2})
+	now := l.last
+	if l.reserveGlobal(now) != 0 || l.reserveGlobal(now) != 0 {
+		t.Fatal("burst of 2 should pass")
+	}
+	if d := l.reserveGlobal(now); d != 500*time.Millisecond {
+		t.Fatalf("third message waits %s, want 500ms", d)
+	}
+}
@@ -25,11 +25,6 @@ type User struct {
 	Username  string `json:"username,omitempty"`
 }
 
-type telegramResponse struct {
-	Ok     bool     `json:"ok"`
-	Result []Update `json:"result"`
-}
-
 // Inline keyboard support
 
 type InlineKeyboardButton struct {
@@ -1,17 +1,15 @@
 package telegram
 
 import (
-	"bytes"
-	"encoding/json"
-	"io"
-	"net/http"
+	"context"
 
-	appErr "go_scripts/internal/errors"
 	"go_scripts/internal/logger"
 )
 
-type sendMessage struct {
-	ChatId      int64               `json:"chat_id"`
+// SendMessageParams — параметры sendMessage. ReplyMarkup — любая разметка
+// Bot API (InlineKeyboardMarkup и т.п.).
+type SendMessageParams struct {
+	ChatID      int64               `json:"chat_id"`
 	Text        string              `json:"text"`
 	ParseMode   string              `json:"parse_mode,omitempty"`
 	LinkPreview *LinkPreviewOptions `json:"link_preview_options,omitempty"`
@@ -27,54 +25,27 @@ type LinkPreviewOptions struct {
 	ShowAboveText    bool   `json:"show_above_text,omitempty"`
 }
 
-func SendMessage(botURL string, chatID int64, text string) error {
-	body := sendMessage{ChatId: chatID, Text: text, ParseMode: "HTML"}
-	buf, _ := json.Marshal(body)
-	resp, err := http.Post(botURL+"/sendMessage", "application/json", bytes.NewBuffer(buf))
-	if err != nil {
-		logger.TelegramError("Отправка сообщения: %v", err)
-		return appErr.NewNetworkError("Ошибка отправки", err)
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(resp.Body)
-		logger.TelegramError("Ошибка API (код %d): %s", resp.StatusCode, string(body))
-		return appErr.NewTelegramError("Ошибка API Telegram", nil)
-	}
-	logger.TelegramInfo("Сообщение отправлено")
-	return nil
+// SendMessage отправляет сообщение через общую очередь клиента и возвращает
+// его в том виде, в каком его сохранил Telegram (с message_id).
+func (c *Client) SendMessage(ctx context.Context, p SendMessageParams) (*Message, error) {
+	var m Message
+	if err := c.callChat(ctx, p.ChatID, "sendMessage", p, &m); err != nil {
+		logger.TelegramError("Отправка сообщения в чат %d: %v", p.ChatID, err)
+		return nil, err
+	}
+	logger.TelegramInfo("Сообщение %d отправлено в чат %d", m.MessageID, m.Chat.ID)
+	return &m, nil
 }
 
-func SendMessageWithPreview(botURL string, chatID int64, text string, previewURL string, large bool, showAbove bool) error {
-	body := sendMessage{
-		ChatId:    chatID,
-		Text:      text,
-		ParseMode: "HTML",
-		LinkPreview: &LinkPreviewOptions{
-			URL:              previewURL,
-			PreferLargeMedia: large,
-			ShowAboveText:    showAbove,
-		},
-	}
-	buf, _ := json.Marshal(body)
-	resp, err := http.Post(botURL+"/sendMessage", "application/json", bytes.NewBuffer(buf))
-	if err != nil {
-		logger.TelegramError("Отправка сообщения: %v", err)
-		return appErr.NewNetworkError("Ошибка отправки", err)
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(resp.Body)
-		logger.TelegramError("Ошибка API (код %d): %s", resp.StatusCode, string(body))
-		return appErr.NewTelegramError("Ошибка API Telegram", nil)
-	}
-	logger.TelegramInfo("Сообщение отправлено")
-	return nil
+// Send отправляет текст в HTML-разметке.
+func (c *Client) Send(ctx context.Context, chatID int64, text string) (*Message, error) {
+	return c.SendMessage(ctx, SendMessageParams{ChatID: chatID, Text: text, ParseMode: "HTML"})
 }
 
-func SendMessageWithPreviewAndKeyboard(botURL string, chatID int64, text string, previewURL string, large bool, showAbove bool, markup InlineKeyboardMarkup) error {
-	body := sendMessage{
-		ChatId:    chatID,
+// SendWithPreview отправляет HTML-текст с превью previewURL; markup может быть nil.
+func (c *Client) SendWithPreview(ctx context.Context, chatID int64, text, previewURL string, large, showAbove bool, markup interface{}) (*Message, error) {
+	return c.SendMessage(ctx, SendMessageParams{
+		ChatID:    chatID,
 		Text:      text,
 		ParseMode: "HTML",
 		LinkPreview: &LinkPreviewOptions{
@@ -83,19 +54,5 @@ func SendMessageWithPreviewAndKeyboard(botURL string, chatID int64, text string,
 			ShowAboveText:    showAbove,
 		},
 		ReplyMarkup: markup,
-	}
-	buf, _ := json.Marshal(body)
-	resp, err := http.Post(botURL+"/sendMessage", "application/json", bytes.NewBuffer(buf))
-	if err != nil {
-		logger.TelegramError("Отправка сообщения: %v", err)
-		return appErr.NewNetworkError("Ошибка отправки", err)
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(resp.Body)
-		logger.TelegramError("Ошибка API (код %d): %s", resp.StatusCode, string(body))
-		return appErr.NewTelegramError("Ошибка API Telegram", nil)
-	}
-	logger.TelegramInfo("Сообщение отправлено")
-	return nil
+	})
 }
@@ -0,0 +1,27 @@
+package telegram
+
+import "context"
+
+// SecretTokenHeader — заголовок, в котором Telegram присылает secret_token,
+// заданный в setWebhook.
+const SecretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// WebhookParams — параметры setWebhook.
+type WebhookParams struct {
+	URL                string   `json:"url"`
+	SecretToken        string   `json:"secret_token,omitempty"`
+	MaxConnections     int      `json:"max_connections,omitempty"`
+	AllowedUpdates     []string `json:"allowed_updates,omitempty"`
+	DropPendingUpdates bool     `json:"drop_pending_updates,omitempty"`
+}
+
+// SetWebhook включает доставку обновлений на p.URL; getUpdates после этого
+// отвечает 409.
+func (c *Client) SetWebhook(ctx context.Context, p WebhookParams) error {
+	return c.Call(ctx, "setWebhook", p, nil)
+}
+
+// DeleteWebhook выключает webhook и возвращает бота к getUpdates.
+func (c *Client) DeleteWebhook(ctx context.Context, dropPending bool) error {
+	return c.Call(ctx, "deleteWebhook", map[string]bool{"drop_pending_updates": dropPending}, nil)
+}
@@ -0,0 +1,79 @@
+package parsers
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Selector описывает, как достать значение из HTML: CSS-селектор
+// (синтаксис cascadia, включая :containsOwn/:matchesOwn) и атрибут.
+// Пустой Attr означает текст узла.
+type Selector struct {
+	CSS  string `json:"css" yaml:"css"`
+	Attr string `json:"attr,omitempty" yaml:"attr,omitempty"`
+}
+
+// First возвращает значение первого подходящего узла или "".
+func (s Selector) First(root *goquery.Selection) string {
+	if s.CSS == "" {
+		return ""
+	}
+	for _, v := range s.All(root) {
+		return v
+	}
+	return ""
+}
+
+// All возвращает непустые значения всех подходящих узлов в порядке документа.
+func (s Selector) All(root *goquery.Selection) []string {
+	if s.CSS == "" {
+		return nil
+	}
+	var out []string
+	root.Find(s.CSS).Each(func(_ int, n *goquery.Selection) {
+		var v string
+		if s.Attr != "" {
+			v, _ = n.Attr(s.Attr)
+			v = strings.TrimSpace(v)
+		} else {
+			v = collapseSpaces(n.Text())
+		}
+		if v != "" {
+			out = append(out, v)
+		}
+	})
+	return out
+}
+
+// firstNonEmpty перебирает селекторы по порядку и возвращает результат первого,
+// который что-то нашёл.
+func firstNonEmpty(root *goquery.Selection, sels []Selector) []string {
+	for _, s := range sels {
+		if vals := s.All(root); len(vals) > 0 {
+			return vals
+		}
+	}
+	return nil
+}
+
+func parseDocument(html string) (*goquery.Document, error) {
+	return goquery.NewDocumentFromReader(strings.NewReader(html))
+}
+
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func dedupe(items []string) []string {
+	seen := make(map[string]struct{}, len(items))
+	out := make([]string, 0, len(items))
+	for _, it := range items {
+		if _, ok := seen[it]; ok {
+			continue
+		}
+		seen[it] = struct{}{}
+		out = append(out, it)
+	}
+	return out
+}
@@ -0,0 +1,222 @@
+package parsers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	neturl "net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	appErr "go_scripts/internal/errors"
+)
+
+const defaultUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36"
+
+// HTTPOptions — настройки исходящих запросов парсеров.
+type HTTPOptions struct {
+	Timeout        time.Duration     // общий таймаут запроса
+	ConnectTimeout time.Duration     // таймаут установки соединения и TLS
+	UserAgent      string            // пусто — defaultUserAgent
+	Headers        map[string]string // дополнительные заголовки (Accept-Language, Referer, ...)
+	ProxyURL       string            // http(s)/socks5 прокси; пусто — из окружения
+	CookieJar      bool              // хранить cookies между запросами
+	Politeness     PolitenessOptions // ограничения частоты запросов к одному хосту
+}
+
+// DefaultHTTPOptions соответствует прежнему поведению парсеров.
+func DefaultHTTPOptions() HTTPOptions {
+	return HTTPOptions{
+		Timeout:        15 * time.Second,
+		ConnectTimeout: 10 * time.Second,
+		UserAgent:      defaultUserAgent,
+		Headers:        map[string]string{"Accept-Language": "ru-RU,ru;q=0.9,en-US;q=0.8,en;q=0.7"},
+		Politeness:     DefaultPolitenessOptions(),
+	}
+}
+
+// Fetcher загружает страницы для парсеров через общий http.Client,
+// соблюдая ограничения частоты запросов к каждому хосту.
+type Fetcher struct {
+	Client  *http.Client
+	Headers http.Header
+
+	limiter *hostLimiter
+}
+
+// StatusError — ответ сервера с кодом, отличным от 200.
+type StatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+	RetryAfter time.Duration // из заголовка Retry-After, если был
+}
+
+func (e *StatusError) Error() string { return "ошибка сервера: " + e.Status }
+
+// NewHTTPClient строит http.Client по настройкам.
+func NewHTTPClient(o HTTPOptions) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if o.ConnectTimeout > 0 {
+		transport.DialContext = (&net.Dialer{Timeout: o.ConnectTimeout, KeepAlive: 30 * time.Second}).DialContext
+		transport.TLSHandshakeTimeout = o.ConnectTimeout
+	}
+	if o.ProxyURL != "" {
+		pu, err := neturl.Parse(o.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("некорректный прокси: %v", err)
+		}
+		transport.Proxy = http.ProxyURL(pu)
+	}
+	client := &http.Client{Timeout: o.Timeout, Transport: transport}
+	if o.CookieJar {
+		jar, err := cookiejar.New(nil)
+		if err != nil {
+			return nil, fmt.Errorf("cookie jar: %v", err)
+		}
+		client.Jar = jar
+	}
+	return client, nil
+}
+
+// NewFetcher создаёт Fetcher с клиентом, построенным по настройкам.
+func NewFetcher(o HTTPOptions) (*Fetcher, error) {
+	client, err := NewHTTPClient(o)
+	if err != nil {
+		return nil, err
+	}
+	h := http.Header{}
+	ua := o.UserAgent
+	if ua == "" {
+		ua = defaultUserAgent
+	}
+	h.Set("User-Agent", ua)
+	for k, v := range o.Headers {
+		h.Set(k, v)
+	}
+	return &Fetcher{Client: client, Headers: h, limiter: newHostLimiter(o.Politeness)}, nil
+}
+
+// GetString выполняет GET и возвращает тело ответа. Если Referer не задан
+// в заголовках, подставляется корень сайта запрашиваемой страницы.
+// На 429/503 запрос повторяется после паузы из Retry-After, а все запросы
+// к этому хосту приостанавливаются на то же время.
+func (f *Fetcher) GetString(ctx context.Context, url string) (string, error) {
+	body, err := f.GetBytes(ctx, url)
+	return string(body), err
+}
+
+// GetBytes — как GetString, но возвращает тело как есть (для изображений).
+func (f *Fetcher) GetBytes(ctx context.Context, url string) ([]byte, error) {
+	return f.GetBytesWithReferer(ctx, url, "")
+}
+
+// GetBytesWithReferer — GetBytes с явным Referer: CDN изображений часто
+// отдают файлы только со страницы работы.
+func (f *Fetcher) GetBytesWithReferer(ctx context.Context, url, referer string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки: %v", err)
+	}
+	for k, vs := range f.Headers {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	if referer != "" {
+		req.Header.Set("Referer", referer)
+	} else if req.Header.Get("Referer") == "" {
+		req.Header.Set("Referer", req.URL.Scheme+"://"+req.URL.Host+"/")
+	}
+
+	host := req.URL.Host
+	for attempt := 0; ; attempt++ {
+		body, err := f.do(ctx, host, req)
+		var se *StatusError
+		if !errors.As(err, &se) || !retryableStatus(se.StatusCode) || f.limiter == nil || attempt >= f.limiter.opts.MaxRetries {
+			return body, classifyFetchError(url, err)
+		}
+		f.limiter.penalize(host, f.limiter.retryDelay(se.RetryAfter, attempt))
+	}
+}
+
+func (f *Fetcher) do(ctx context.Context, host string, req *http.Request) ([]byte, error) {
+	if f.limiter != nil {
+		release, err := f.limiter.acquire(ctx, host)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка загрузки: %w", err)
+		}
+		defer release()
+	}
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		se := &StatusError{URL: req.URL.String(), StatusCode: resp.StatusCode, Status: resp.Status}
+		se.RetryAfter, _ = parseRetryAfter(resp.Header.Get("Retry-After"))
+		return nil, se
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения: %w", err)
+	}
+	return body, nil
+}
+
+// classifyFetchError превращает ошибку загрузки в AppError: таймауты, обрывы
+// соединения, 5xx и 429 — временные, остальные коды ответа — постоянные.
+// Отмена контекста возвращается как есть.
+func classifyFetchError(url string, err error) error {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return err
+	}
+	var se *StatusError
+	if errors.As(err, &se) {
+		e := appErr.NewNetworkError("ошибка загрузки "+url, se).WithCode(strconv.Itoa(se.StatusCode))
+		if se.StatusCode == http.StatusTooManyRequests || se.StatusCode >= 500 {
+			return e.WithRetryAfter(se.RetryAfter)
+		}
+		return e
+	}
+	return appErr.NewNetworkError("ошибка загрузки "+url, err).WithTransient(true)
+}
+
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable
+}
+
+var (
+	defaultFetcherMu sync.RWMutex
+	defaultFetcher   *Fetcher
+)
+
+// SetDefaultFetcher задаёт Fetcher, которым пользуются парсеры без собственного.
+func SetDefaultFetcher(f *Fetcher) {
+	defaultFetcherMu.Lock()
+	defer defaultFetcherMu.Unlock()
+	defaultFetcher = f
+}
+
+// DefaultFetcher возвращает Fetcher по умолчанию, создавая его при первом вызове.
+func DefaultFetcher() *Fetcher {
+	defaultFetcherMu.RLock()
+	f := defaultFetcher
+	defaultFetcherMu.RUnlock()
+	if f != nil {
+		return f
+	}
+	defaultFetcherMu.Lock()
+	defer defaultFetcherMu.Unlock()
+	if defaultFetcher == nil {
+		// DefaultHTTPOptions не содержит прокси, поэтому ошибки быть не может
+		defaultFetcher, _ = NewFetcher(DefaultHTTPOptions())
+	}
+	return defaultFetcher
+}
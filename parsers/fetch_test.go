@@ -0,0 +1,107 @@
+package parsers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	appErr "go_scripts/internal/errors"
+)
+
+// TestMain отключает паузы между запросами для тестов, которые идут через
+// DefaultFetcher (фикстуры отдаёт локальный сервер).
+func TestMain(m *testing.M) {
+	o := DefaultHTTPOptions()
+	o.Politeness = PolitenessOptions{}
+	f, err := NewFetcher(o)
+	if err != nil {
+		panic(err)
+	}
+	SetDefaultFetcher(f)
+	os.Exit(m.Run())
+}
+
+func newTestFetcher(t *testing.T, p PolitenessOptions) *Fetcher {
+	t.Helper()
+	o := DefaultHTTPOptions()
+	o.Politeness = p
+	f, err := NewFetcher(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestFetcherRetriesAfter429(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	f := newTestFetcher(t, PolitenessOptions{MaxRetries: 2})
+	start := time.Now()
+	body, err := f.GetString(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if body != "ok" || atomic.LoadInt32(&calls) != 2 {
+		t.Fatalf("body=%q calls=%d", body, calls)
+	}
+	if time.Since(start) < time.Second {
+		t.Errorf("Retry-After was not honoured: elapsed %s", time.Since(start))
+	}
+}
+
+func TestFetcherStatusError(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	f := newTestFetcher(t, PolitenessOptions{MaxRetries: 2})
+	_, err := f.GetString(context.Background(), srv.URL)
+	var se *StatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 StatusError, got %v", err)
+	}
+	if appErr.IsTransient(err) {
+		t.Errorf("404 must be permanent: %v", err)
+	}
+}
+
+func TestFetcherServerErrorIsTransient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	f := newTestFetcher(t, PolitenessOptions{})
+	if _, err := f.GetString(context.Background(), srv.URL); !appErr.IsTransient(err) {
+		t.Fatalf("502 must be transient, got %v", err)
+	}
+}
+
+func TestFetcherRateLimitPerHost(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	f := newTestFetcher(t, PolitenessOptions{RatePerSec: 10, Burst: 1})
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := f.GetString(context.Background(), srv.URL); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("3 requests at 10 rps took only %s", elapsed)
+	}
+}
@@ -0,0 +1,134 @@
+package parsers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// go test ./parsers -run TestHentaichanGolden -update
+var update = flag.Bool("update", false, "перезаписать golden.json в testdata/golden")
+
+// serverPlaceholder заменяет адрес тестового сервера в golden-файлах,
+// чтобы относительные ссылки не зависели от порта.
+const serverPlaceholder = "{{server}}"
+
+// goldenWork — поля ParsedWork, которые сверяются с golden-файлом.
+type goldenWork struct {
+	Title       string   `json:"title"`
+	Series      string   `json:"series"`
+	Authors     []string `json:"authors"`
+	Translators []string `json:"translators"`
+	Tags        []string `json:"tags"`
+	Images      []string `json:"images"`
+}
+
+// newFixtureServer отдаёт записанные manga.html и online.html из dir
+// по путям /manga/... и /online/... как настоящий сайт.
+func newFixtureServer(t *testing.T, dir string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var name string
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/manga/"):
+			name = "manga.html"
+		case strings.HasPrefix(r.URL.Path, "/online/"):
+			name = "online.html"
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(b)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func toGolden(w *ParsedWork, serverURL string) goldenWork {
+	images := w.PageURLs()
+	for i, u := range images {
+		images[i] = strings.Replace(u, serverURL, serverPlaceholder, 1)
+	}
+	return goldenWork{
+		Title:       w.Title,
+		Series:      w.Series,
+		Authors:     w.Authors,
+		Translators: w.Translators,
+		Tags:        w.Tags,
+		Images:      images,
+	}
+}
+
+func TestHentaichanGolden(t *testing.T) {
+	dirs, err := filepath.Glob(filepath.Join("testdata", "golden", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) == 0 {
+		t.Skip("no golden cases")
+	}
+	for _, dir := range dirs {
+		name := filepath.Base(dir)
+		t.Run(name, func(t *testing.T) {
+			srv := newFixtureServer(t, dir)
+			w, err := HentaichanParseAll(context.Background(), srv.URL+"/online/"+name+".html")
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			got, err := json.MarshalIndent(toGolden(w, srv.URL), "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			path := filepath.Join(dir, "golden.json")
+			if *update {
+				if err := os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatalf("write golden: %v", err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("read golden (run with -update to create): %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("result differs from %s (run with -update if the change is expected):\n%s", path, diffLines(string(want), string(got)))
+			}
+		})
+	}
+}
+
+// diffLines — простой построчный diff для сообщений об ошибках.
+func diffLines(want, got string) string {
+	w := strings.Split(want, "\n")
+	g := strings.Split(got, "\n")
+	var b strings.Builder
+	for i := 0; i < len(w) || i < len(g); i++ {
+		var wl, gl string
+		if i < len(w) {
+			wl = w[i]
+		}
+		if i < len(g) {
+			gl = g[i]
+		}
+		if wl != gl {
+			fmt.Fprintf(&b, "line %d:\n  - %s\n  + %s\n", i+1, wl, gl)
+		}
+	}
+	return b.String()
+}
@@ -1,104 +1,201 @@
 package parsers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	neturl "net/url"
 	"regexp"
 	"strings"
-	"time"
 )
 
-func HentaichanParser(url string) (string, []string, error) {
-	info, err := HentaichanParseAll(url)
+func HentaichanParser(ctx context.Context, url string) (string, []string, error) {
+	work, err := HentaichanParseAll(ctx, url)
 	if err != nil {
 		return "", nil, err
 	}
-	return info.Title, info.ImageURLs, nil
+	return work.Title, work.PageURLs(), nil
 }
 
-// HentaichanInfo содержит данные с обеих страниц (/manga/ и /online/)
-type HentaichanInfo struct {
-	Title      string
-	Series     string
-	Author     string
-	Translator string
-	Tags       []string
-	ImageURLs  []string
+func init() { Register(HentaichanSource{}) }
+
+// HentaichanSelectors — селекторы полей h-chan. При изменении вёрстки сайта
+// достаточно поправить их в файле HENTAICHAN_SELECTORS (см.
+// LoadHentaichanSelectors), не трогая код парсера.
+type HentaichanSelectors struct {
+	Title      Selector   `json:"title" yaml:"title"`           // название на /manga/
+	Series     Selector   `json:"series" yaml:"series"`         // "Аниме/манга"
+	Author     Selector   `json:"author" yaml:"author"`         // "Автор"
+	Translator Selector   `json:"translator" yaml:"translator"` // "Переводчик"
+	Tags       Selector   `json:"tags" yaml:"tags"`             // теги в боковом списке, без ссылок "+" и "-"
+	Images     []Selector `json:"images" yaml:"images"`         // <img> на /online/, пробуются по порядку
+}
+
+// DefaultHentaichanSelectors соответствует текущей вёрстке h-chan.
+var DefaultHentaichanSelectors = HentaichanSelectors{
+	Title:      Selector{CSS: "h1"},
+	Series:     hentaichanField("Аниме/манга"),
+	Author:     hentaichanField("Автор"),
+	Translator: hentaichanField("Переводчик"),
+	Tags:       Selector{CSS: `li.sidetag a:not(:matchesOwn(^\s*[-+]?\s*$))`},
+	Images: []Selector{
+		{CSS: "img[data-src]", Attr: "data-src"},
+		{CSS: "img[src]", Attr: "src"},
+	},
+}
+
+// hentaichanField выбирает значение блока вида
+// <div class="item">label</div><div class="item2"><h2>value</h2></div>.
+func hentaichanField(label string) Selector {
+	return Selector{CSS: `div.item:matchesOwn(^\s*` + regexp.QuoteMeta(label) + `\s*$) + div.item2 h2`}
+}
+
+// HentaichanSource — реализация SourceParser для h-chan.
+// Nil Selectors означает DefaultHentaichanSelectors, nil Fetcher — DefaultFetcher().
+type HentaichanSource struct {
+	Selectors *HentaichanSelectors
+	Fetcher   *Fetcher
+}
+
+// LoadHentaichanSelectors читает селекторы из YAML- или JSON-файла. Поля,
+// которых нет в файле, берутся из DefaultHentaichanSelectors.
+func LoadHentaichanSelectors(path string) (*HentaichanSelectors, error) {
+	sel := DefaultHentaichanSelectors
+	sel.Images = append([]Selector(nil), sel.Images...)
+	if err := decodeFile(path, &sel); err != nil {
+		return nil, err
+	}
+	if sel.Title.CSS == "" || len(sel.Images) == 0 {
+		return nil, fmt.Errorf("%s: title и images не могут быть пустыми", path)
+	}
+	return &sel, nil
+}
+
+// ConfigureHentaichan перерегистрирует парсер h-chan с селекторами из файла
+// path; пустой path оставляет селекторы по умолчанию.
+func ConfigureHentaichan(path string) error {
+	if path == "" {
+		return nil
+	}
+	sel, err := LoadHentaichanSelectors(path)
+	if err != nil {
+		return err
+	}
+	Register(HentaichanSource{Selectors: sel})
+	return nil
+}
+
+func (HentaichanSource) Name() string { return "hentaichan" }
+
+func (HentaichanSource) Hosts() []string {
+	return []string{"h-chan.me", "hentaichan.live", "hentai-chan.pro"}
+}
+
+func (s HentaichanSource) Match(u *neturl.URL) bool {
+	if !hostMatches(u, s.Hosts()) {
+		return false
+	}
+	return strings.Contains(u.Path, "/manga/") || strings.Contains(u.Path, "/online/")
+}
+
+func (s HentaichanSource) Parse(ctx context.Context, url string) (*ParsedWork, error) {
+	f := s.Fetcher
+	if f == nil {
+		f = DefaultFetcher()
+	}
+	return hentaichanParse(ctx, f, s.selectors(), url)
+}
+
+func (s HentaichanSource) selectors() *HentaichanSelectors {
+	if s.Selectors != nil {
+		return s.Selectors
+	}
+	return &DefaultHentaichanSelectors
 }
 
 // HentaichanParseAll загружает обе страницы (manga и online) и извлекает нужные данные.
-func HentaichanParseAll(inputURL string) (*HentaichanInfo, error) {
-	client := &http.Client{Timeout: 15 * time.Second}
+func HentaichanParseAll(ctx context.Context, inputURL string) (*ParsedWork, error) {
+	return hentaichanParse(ctx, DefaultFetcher(), &DefaultHentaichanSelectors, inputURL)
+}
 
+func hentaichanParse(ctx context.Context, f *Fetcher, sel *HentaichanSelectors, inputURL string) (*ParsedWork, error) {
 	mangaURL, onlineURL := derivePairURLs(inputURL)
-
-	mangaHTML, err := httpGetString(client, mangaURL)
+	mangaHTML, onlineHTML, err := fetchPair(ctx, f, mangaURL, onlineURL)
 	if err != nil {
 		return nil, err
 	}
-	onlineHTML, err := httpGetString(client, onlineURL)
+	return hentaichanExtract(sel, mangaURL, onlineURL, mangaHTML, onlineHTML)
+}
+
+// HentaichanFetchPages загружает HTML страниц /manga/ и /online/ для ссылки
+// на любую из них (используется и для записи фикстур).
+func HentaichanFetchPages(ctx context.Context, f *Fetcher, inputURL string) (string, string, error) {
+	mangaURL, onlineURL := derivePairURLs(inputURL)
+	return fetchPair(ctx, f, mangaURL, onlineURL)
+}
+
+func fetchPair(ctx context.Context, f *Fetcher, mangaURL, onlineURL string) (string, string, error) {
+	mangaHTML, err := f.GetString(ctx, mangaURL)
 	if err != nil {
-		return nil, err
+		return "", "", err
+	}
+	onlineHTML, err := f.GetString(ctx, onlineURL)
+	if err != nil {
+		return "", "", err
 	}
+	return mangaHTML, onlineHTML, nil
+}
+
+// hentaichanExtract разбирает уже загруженные страницы /manga/ и /online/.
+func hentaichanExtract(sel *HentaichanSelectors, mangaURL, onlineURL, mangaHTML, onlineHTML string) (*ParsedWork, error) {
+	mangaDoc, err := parseDocument(mangaHTML)
+	if err != nil {
+		return nil, fmt.Errorf("разбор страницы manga: %v", err)
+	}
+	onlineDoc, err := parseDocument(onlineHTML)
+	if err != nil {
+		return nil, fmt.Errorf("разбор страницы online: %v", err)
+	}
+	manga := mangaDoc.Selection
 
 	// meta from manga page
-	title := extractTitle(mangaHTML)
+	title := sel.Title.First(manga)
+	if title == "" {
+		title = extractJSONName(mangaHTML)
+	}
 	if title == "" {
 		// fallback to JSON meta name from online page
 		title = extractJSONName(onlineHTML)
 	}
-	series := extractField(mangaHTML, "Аниме/манга")
-	author := extractField(mangaHTML, "Автор")
-	translator := extractField(mangaHTML, "Переводчик")
-	tags := extractTags(mangaHTML)
+	series := sel.Series.First(manga)
+	author := sel.Author.First(manga)
+	translator := sel.Translator.First(manga)
+	tags := dedupe(sel.Tags.All(manga))
 
 	// images from online page
 	imgs := extractFullImgArray(onlineHTML)
 	if len(imgs) == 0 {
-		imgs = extractImgTags(onlineHTML)
+		imgs = filterImageURLs(firstNonEmpty(onlineDoc.Selection, sel.Images))
 	}
 	if len(imgs) == 0 {
 		imgs = extractAnyQuotedImages(onlineHTML)
 	}
 	imgs = normalizeURLs(onlineURL, imgs)
 
-	return &HentaichanInfo{
-		Title:      title,
-		Series:     series,
-		Author:     author,
-		Translator: translator,
-		Tags:       tags,
-		ImageURLs:  imgs,
+	return &ParsedWork{
+		Source:      "hentaichan",
+		SourceID:    hentaichanSourceID(mangaURL),
+		SourceURL:   mangaURL,
+		Title:       title,
+		Series:      series,
+		Authors:     splitNames(author),
+		Translators: splitNames(translator),
+		Tags:        tags,
+		Language:    "ru",
+		Pages:       NewPages(imgs),
 	}, nil
 }
 
-func httpGetString(client *http.Client, url string) (string, error) {
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return "", fmt.Errorf("ошибка загрузки: %v", err)
-	}
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36")
-	req.Header.Set("Accept-Language", "ru-RU,ru;q=0.9,en-US;q=0.8,en;q=0.7")
-	req.Header.Set("Referer", "https://x5.h-chan.me/")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("ошибка загрузки: %v", err)
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("ошибка сервера: %s", resp.Status)
-	}
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("ошибка чтения: %v", err)
-	}
-	return string(body), nil
-}
-
 func derivePairURLs(input string) (string, string) {
 	u, err := neturl.Parse(input)
 	if err != nil {
@@ -125,17 +222,10 @@ func derivePairURLs(input string) (string, string) {
 	return manga, online
 }
 
-func extractTitle(html string) string {
-	// Prefer <h1>..</h1>
-	reH1 := regexp.MustCompile(`(?is)<h1[^>]*>(.*?)</h1>`)
-	if m := reH1.FindStringSubmatch(html); len(m) == 2 {
-		t := strings.TrimSpace(stripHTML(m[1]))
-		if t != "" {
-			return t
-		}
-	}
-	// Fallback to JSON meta name
-	return extractJSONName(html)
+// hentaichanSourceID возвращает slug работы без расширения, например "12345-title".
+func hentaichanSourceID(mangaURL string) string {
+	slug := mangaURL[strings.LastIndex(mangaURL, "/")+1:]
+	return strings.TrimSuffix(slug, ".html")
 }
 
 func extractJSONName(s string) string {
@@ -146,59 +236,14 @@ func extractJSONName(s string) string {
 	return ""
 }
 
-func extractField(html, label string) string {
-	// Match a block: <div class="item">label</div> ... <h2> ... </h2>
-	re := regexp.MustCompile(`(?is)<div\s+class=["']item["']>\s*` + regexp.QuoteMeta(label) + `\s*</div>\s*<div\s+class=["']item2["']>\s*<h2>(.*?)</h2>`)
-	if m := re.FindStringSubmatch(html); len(m) == 2 {
-		v := strings.TrimSpace(stripHTML(m[1]))
-		return v
-	}
-	return ""
-}
-
-func extractTags(html string) []string {
-	// Find tags in side list; ignore "+" and "-" entries
-	re := regexp.MustCompile(`(?is)<li\s+class=["']sidetag["'][^>]*>(.*?)</li>`) // capture li content
-	var tags []string
-	for _, li := range re.FindAllStringSubmatch(html, -1) {
-		if len(li) != 2 {
-			continue
-		}
-		// extract all anchors' inner texts
-		aRe := regexp.MustCompile(`(?is)<a[^>]*>([^<]+)</a>`)
-		as := aRe.FindAllStringSubmatch(li[1], -1)
-		if len(as) == 0 {
-			continue
-		}
-		for _, a := range as {
-			if len(a) != 2 {
-				continue
-			}
-			text := strings.TrimSpace(stripHTML(a[1]))
-			if text == "+" || text == "-" || text == "" {
-				continue
-			}
-			// candidate tag text
-			tags = append(tags, text)
-			break // take the first non +/- text per li
-		}
-	}
-	// dedupe preserving order
-	seen := map[string]struct{}{}
-	out := make([]string, 0, len(tags))
-	for _, t := range tags {
-		if _, ok := seen[t]; ok {
-			continue
-		}
-		seen[t] = struct{}{}
-		out = append(out, t)
-	}
-	return out
+func extractFullImgArray(s string) []string {
+	return extractScriptArray(s, "fullimg")
 }
 
-func extractFullImgArray(s string) []string {
+// extractScriptArray достаёт JS-массив строк вида "key": ['a', 'b',] из скрипта.
+func extractScriptArray(s, key string) []string {
 	var urls []string
-	reImgs := regexp.MustCompile(`(?is)["']fullimg["']\s*:\s*\[(.*?)\]`)
+	reImgs := regexp.MustCompile(`(?is)["']` + regexp.QuoteMeta(key) + `["']\s*:\s*\[(.*?)\]`)
 	if m := reImgs.FindStringSubmatch(s); len(m) == 2 {
 		arr := "[" + m[1] + "]"
 		arr = strings.ReplaceAll(arr, "'", "\"")
@@ -208,15 +253,21 @@ func extractFullImgArray(s string) []string {
 	return urls
 }
 
-func extractImgTags(s string) []string {
-	var urls []string
-	reTagImgs := regexp.MustCompile(`(?is)<img[^>]+(?:data-src|src)=["']([^"']+\.(?:jpe?g|png|webp))(?:\?[^"']*)?["']`)
-	for _, m := range reTagImgs.FindAllStringSubmatch(s, -1) {
-		if len(m) == 2 {
-			urls = append(urls, strings.TrimSpace(m[1]))
+var reImageExt = regexp.MustCompile(`(?i)\.(?:jpe?g|png|webp)(?:\?.*)?$`)
+
+// filterImageURLs оставляет только ссылки на jpg/png/webp, отбрасывая query.
+func filterImageURLs(urls []string) []string {
+	out := make([]string, 0, len(urls))
+	for _, u := range urls {
+		if !reImageExt.MatchString(u) {
+			continue
 		}
+		if i := strings.Index(u, "?"); i >= 0 {
+			u = u[:i]
+		}
+		out = append(out, u)
 	}
-	return urls
+	return out
 }
 
 func extractAnyQuotedImages(s string) []string {
@@ -253,22 +304,3 @@ func normalizeURLs(baseURL string, urls []string) []string {
 	}
 	return norm
 }
-
-// stripHTML removes HTML tags; simplistic but adequate for titles
-func stripHTML(s string) string {
-	var b strings.Builder
-	inTag := false
-	for _, r := range s {
-		switch r {
-		case '<':
-			inTag = true
-		case '>':
-			inTag = false
-		default:
-			if !inTag {
-				b.WriteRune(r)
-			}
-		}
-	}
-	return b.String()
-}
@@ -0,0 +1,126 @@
+package parsers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const (
+	testMangaURL  = "https://x5.h-chan.me/manga/46143-letnie-kanikuly.html"
+	testOnlineURL = "https://x5.h-chan.me/online/46143-letnie-kanikuly.html"
+)
+
+func readFixture(t *testing.T, name string) string {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", "hentaichan", name))
+	if err != nil {
+		t.Fatalf("read fixture %s: %v", name, err)
+	}
+	return string(b)
+}
+
+func TestHentaichanExtract(t *testing.T) {
+	wantImages := []string{
+		"https://img4.h-chan.me/manganew/l/letnie/01.jpg",
+		"https://img4.h-chan.me/manganew/l/letnie/02.jpg",
+		"https://img4.h-chan.me/manganew/l/letnie/03.png",
+	}
+	cases := []struct {
+		name   string
+		manga  string
+		online string
+		images []string
+	}{
+		{"fullimg array", "manga.html", "online.html", wantImages},
+		{"shifted markup", "manga_shifted.html", "online.html", wantImages},
+		{"img tags fallback", "manga.html", "online_imgtags.html", []string{
+			"https://img4.h-chan.me/manganew/l/letnie/01.jpg",
+			"https://x5.h-chan.me/manganew/l/letnie/02.jpg",
+			"https://img4.h-chan.me/manganew/l/letnie/03.png",
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w, err := hentaichanExtract(&DefaultHentaichanSelectors, testMangaURL, testOnlineURL, readFixture(t, tc.manga), readFixture(t, tc.online))
+			if err != nil {
+				t.Fatalf("extract: %v", err)
+			}
+			if w.Title != "Летние каникулы" {
+				t.Errorf("title = %q", w.Title)
+			}
+			if w.Series != "Оригинальные работы" {
+				t.Errorf("series = %q", w.Series)
+			}
+			if want := []string{"Tanaka", "Suzuki"}; !reflect.DeepEqual(w.Authors, want) {
+				t.Errorf("authors = %q, want %q", w.Authors, want)
+			}
+			if want := []string{"Shirokuma Team"}; !reflect.DeepEqual(w.Translators, want) {
+				t.Errorf("translators = %q, want %q", w.Translators, want)
+			}
+			if want := []string{"большая грудь", "ванилла", "школьная форма"}; !reflect.DeepEqual(w.Tags, want) {
+				t.Errorf("tags = %q, want %q", w.Tags, want)
+			}
+			if got := w.PageURLs(); !reflect.DeepEqual(got, tc.images) {
+				t.Errorf("images = %q, want %q", got, tc.images)
+			}
+			if w.SourceID != "46143-letnie-kanikuly" {
+				t.Errorf("source id = %q", w.SourceID)
+			}
+		})
+	}
+}
+
+func TestHentaichanExtractCustomSelectors(t *testing.T) {
+	sel := DefaultHentaichanSelectors
+	sel.Title = Selector{CSS: "title"}
+	sel.Author = hentaichanField("Переводчик")
+	w, err := hentaichanExtract(&sel, testMangaURL, testOnlineURL, readFixture(t, "manga.html"), readFixture(t, "online.html"))
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if w.Title != "Летние каникулы » Хентай-тян" {
+		t.Errorf("title = %q", w.Title)
+	}
+	if want := []string{"Shirokuma Team"}; !reflect.DeepEqual(w.Authors, want) {
+		t.Errorf("authors = %q, want %q", w.Authors, want)
+	}
+}
+
+func TestLoadHentaichanSelectors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hentaichan.yaml")
+	if err := os.WriteFile(path, []byte("title: {css: title}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sel, err := LoadHentaichanSelectors(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if sel.Title.CSS != "title" {
+		t.Errorf("title = %+v", sel.Title)
+	}
+	if !reflect.DeepEqual(sel.Series, DefaultHentaichanSelectors.Series) || len(sel.Images) != len(DefaultHentaichanSelectors.Images) {
+		t.Errorf("fields absent from the file must keep defaults: %+v", sel)
+	}
+
+	if err := os.WriteFile(path, []byte("images: []\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadHentaichanSelectors(path); err == nil {
+		t.Error("empty images must be rejected")
+	}
+}
+
+func TestHentaichanExtractTitleFallback(t *testing.T) {
+	w, err := hentaichanExtract(&DefaultHentaichanSelectors, testMangaURL, testOnlineURL, "<html><body></body></html>", readFixture(t, "online.html"))
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if w.Title != "Летние каникулы" {
+		t.Errorf("title = %q", w.Title)
+	}
+	if w.Series != "" || len(w.Authors) != 0 || len(w.Tags) != 0 {
+		t.Errorf("unexpected meta on empty page: %+v", w)
+	}
+}
@@ -0,0 +1,189 @@
+package parsers
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PolitenessOptions ограничивают нагрузку на каждый сайт-источник.
+type PolitenessOptions struct {
+	RatePerSec    float64       // средняя частота запросов к одному хосту; <= 0 — без ограничения
+	Burst         int           // размер «ведра» токенов
+	MaxConcurrent int           // одновременных запросов к одному хосту; <= 0 — без ограничения
+	MinDelay      time.Duration // случайная пауза перед запросом: от MinDelay
+	MaxDelay      time.Duration // до MaxDelay
+	MaxRetries    int           // повторов на 429/503
+	MaxRetryAfter time.Duration // верхняя граница ожидания по Retry-After
+}
+
+// DefaultPolitenessOptions — умеренные значения для массового импорта.
+func DefaultPolitenessOptions() PolitenessOptions {
+	return PolitenessOptions{
+		RatePerSec:    1,
+		Burst:         2,
+		MaxConcurrent: 2,
+		MinDelay:      250 * time.Millisecond,
+		MaxDelay:      time.Second,
+		MaxRetries:    3,
+		MaxRetryAfter: 2 * time.Minute,
+	}
+}
+
+// hostLimiter — token bucket, семафор и «бан-пауза» на каждый хост.
+type hostLimiter struct {
+	opts  PolitenessOptions
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+type hostState struct {
+	tokens       float64
+	last         time.Time
+	blockedUntil time.Time
+	sem          chan struct{}
+}
+
+func newHostLimiter(o PolitenessOptions) *hostLimiter {
+	return &hostLimiter{opts: o, hosts: map[string]*hostState{}}
+}
+
+func (l *hostLimiter) state(host string) *hostState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	st, ok := l.hosts[host]
+	if !ok {
+		st = &hostState{tokens: float64(l.burst()), last: time.Now()}
+		if l.opts.MaxConcurrent > 0 {
+			st.sem = make(chan struct{}, l.opts.MaxConcurrent)
+		}
+		l.hosts[host] = st
+	}
+	return st
+}
+
+func (l *hostLimiter) burst() int {
+	if l.opts.Burst < 1 {
+		return 1
+	}
+	return l.opts.Burst
+}
+
+// acquire ждёт своей очереди к хосту. release нужно вызвать после запроса.
+func (l *hostLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	st := l.state(host)
+	release := func() {}
+	if st.sem != nil {
+		select {
+		case st.sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		release = func() { <-st.sem }
+	}
+	for {
+		wait := l.reserve(st)
+		if wait <= 0 {
+			break
+		}
+		if err := sleepCtx(ctx, wait); err != nil {
+			release()
+			return nil, err
+		}
+	}
+	if err := sleepCtx(ctx, l.jitter()); err != nil {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+// reserve забирает токен или возвращает, сколько нужно подождать.
+func (l *hostLimiter) reserve(st *hostState) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if now.Before(st.blockedUntil) {
+		return st.blockedUntil.Sub(now)
+	}
+	if l.opts.RatePerSec <= 0 {
+		return 0
+	}
+	st.tokens += now.Sub(st.last).Seconds() * l.opts.RatePerSec
+	if max := float64(l.burst()); st.tokens > max {
+		st.tokens = max
+	}
+	st.last = now
+	if st.tokens >= 1 {
+		st.tokens--
+		return 0
+	}
+	return time.Duration((1 - st.tokens) / l.opts.RatePerSec * float64(time.Second))
+}
+
+// penalize приостанавливает все запросы к хосту на d (например, по Retry-After).
+func (l *hostLimiter) penalize(host string, d time.Duration) {
+	st := l.state(host)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := time.Now().Add(d); until.After(st.blockedUntil) {
+		st.blockedUntil = until
+	}
+}
+
+func (l *hostLimiter) jitter() time.Duration {
+	lo, hi := l.opts.MinDelay, l.opts.MaxDelay
+	if hi <= lo {
+		return lo
+	}
+	return lo + time.Duration(rand.Int63n(int64(hi-lo)))
+}
+
+// retryDelay — пауза перед повтором: Retry-After, если сервер его прислал,
+// иначе экспоненциальная (1s, 2s, 4s, ...).
+func (l *hostLimiter) retryDelay(retryAfter time.Duration, attempt int) time.Duration {
+	d := retryAfter
+	if d <= 0 {
+		d = time.Duration(1<<attempt) * time.Second
+	}
+	if l.opts.MaxRetryAfter > 0 && d > l.opts.MaxRetryAfter {
+		d = l.opts.MaxRetryAfter
+	}
+	return d
+}
+
+// parseRetryAfter понимает оба формата заголовка: секунды и HTTP-дату.
+func parseRetryAfter(v string) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+	if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+		return time.Duration(n) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,114 @@
+package parsers
+
+import (
+	"context"
+	"fmt"
+	neturl "net/url"
+	"strings"
+	"sync"
+)
+
+// SourceParser — парсер одного сайта-источника.
+type SourceParser interface {
+	// Name возвращает короткое имя источника (например, "hentaichan").
+	Name() string
+	// Hosts возвращает список доменов, которые обслуживает парсер.
+	Hosts() []string
+	// Match сообщает, умеет ли парсер разбирать данный URL.
+	Match(u *neturl.URL) bool
+	// Parse загружает и разбирает страницу(ы) по URL.
+	Parse(ctx context.Context, url string) (*ParsedWork, error)
+}
+
+// Registry хранит парсеры, проиндексированные по домену.
+type Registry struct {
+	mu      sync.RWMutex
+	byHost  map[string]SourceParser
+	parsers []SourceParser
+}
+
+func NewRegistry() *Registry {
+	return &Registry{byHost: map[string]SourceParser{}}
+}
+
+// Register добавляет парсер; домены из Hosts() становятся ключами реестра.
+// Парсер с тем же Name() заменяет ранее зарегистрированный.
+func (r *Registry) Register(p SourceParser) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, h := range p.Hosts() {
+		r.byHost[normalizeHost(h)] = p
+	}
+	for i, old := range r.parsers {
+		if old.Name() == p.Name() {
+			r.parsers[i] = p
+			return
+		}
+	}
+	r.parsers = append(r.parsers, p)
+}
+
+// Lookup находит парсер для URL: сначала по домену (включая родительские
+// домены, т.е. x5.h-chan.me -> h-chan.me), затем перебором Match —
+// для парсеров, которые узнают свои адреса не только по Hosts(). Match
+// каждого парсера сам проверяет домен, поэтому чужой сайт с похожим путём
+// не попадёт к первому подходящему парсеру.
+func (r *Registry) Lookup(rawURL string) (SourceParser, error) {
+	u, err := neturl.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("некорректный URL: %s", rawURL)
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	host := normalizeHost(u.Hostname())
+	for h := host; h != ""; {
+		if p, ok := r.byHost[h]; ok && p.Match(u) {
+			return p, nil
+		}
+		i := strings.Index(h, ".")
+		if i < 0 {
+			break
+		}
+		h = h[i+1:]
+	}
+	for _, p := range r.parsers {
+		if p.Match(u) {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("нет парсера для домена %s", u.Hostname())
+}
+
+// Parsers возвращает зарегистрированные парсеры в порядке регистрации.
+func (r *Registry) Parsers() []SourceParser {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]SourceParser, len(r.parsers))
+	copy(out, r.parsers)
+	return out
+}
+
+// hostMatches сообщает, относится ли хост u к одному из hosts (с учётом
+// поддоменов).
+func hostMatches(u *neturl.URL, hosts []string) bool {
+	host := normalizeHost(u.Hostname())
+	for _, h := range hosts {
+		h = normalizeHost(h)
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeHost(h string) string {
+	h = strings.ToLower(strings.TrimSpace(h))
+	return strings.TrimPrefix(h, "www.")
+}
+
+// Default — глобальный реестр, в который парсеры регистрируются в init().
+var Default = NewRegistry()
+
+func Register(p SourceParser) { Default.Register(p) }
+
+func Lookup(rawURL string) (SourceParser, error) { return Default.Lookup(rawURL) }
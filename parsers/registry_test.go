@@ -0,0 +1,16 @@
+package parsers
+
+import "testing"
+
+func TestRegistryLookupChecksHost(t *testing.T) {
+	r := NewRegistry()
+	r.Register(HentaichanSource{})
+
+	p, err := r.Lookup("https://x5.h-chan.me/manga/46143-letnie-kanikuly.html")
+	if err != nil || p.Name() != "hentaichan" {
+		t.Fatalf("h-chan mirror: parser = %v, err = %v", p, err)
+	}
+	if p, err := r.Lookup("https://example.com/manga/1-test.html"); err == nil {
+		t.Fatalf("foreign /manga/ url routed to %s", p.Name())
+	}
+}
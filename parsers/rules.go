@@ -0,0 +1,287 @@
+package parsers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Rule — декларативное описание простого сайта. Позволяет подключить новый
+// источник файлом YAML/JSON без написания Go-кода.
+//
+//	name: example
+//	hosts: [example.com]
+//	path_pattern: ^/(manga|online)/
+//	language: ru
+//	urls:
+//	  slug: ([^/]+)$
+//	  info: "{scheme}://{host}/manga/{slug}"
+//	  reader: "{scheme}://{host}/online/{slug}"
+//	fields:
+//	  title: {css: h1}
+//	  authors: {css: ".author a"}
+//	  tags: {css: ".tags a"}
+//	images:
+//	  script_key: fullimg
+//	  selectors:
+//	    - {css: "img[data-src]", attr: data-src}
+type Rule struct {
+	Name        string     `json:"name" yaml:"name"`
+	Hosts       []string   `json:"hosts" yaml:"hosts"`
+	PathPattern string     `json:"path_pattern,omitempty" yaml:"path_pattern,omitempty"`
+	Language    string     `json:"language,omitempty" yaml:"language,omitempty"`
+	URLs        RuleURLs   `json:"urls" yaml:"urls"`
+	Fields      RuleFields `json:"fields" yaml:"fields"`
+	Images      RuleImages `json:"images" yaml:"images"`
+}
+
+// RuleURLs описывает, как из входной ссылки получить страницу с информацией
+// и страницу читалки (аналог derivePairURLs). В шаблонах доступны
+// {scheme}, {host}, {path} и {slug}. Пустой шаблон — входная ссылка как есть.
+type RuleURLs struct {
+	Slug   string `json:"slug,omitempty" yaml:"slug,omitempty"` // regexp по path с одной группой; по умолчанию последний сегмент
+	Info   string `json:"info,omitempty" yaml:"info,omitempty"`
+	Reader string `json:"reader,omitempty" yaml:"reader,omitempty"`
+}
+
+// RuleFields — селекторы метаданных на странице с информацией.
+type RuleFields struct {
+	Title       Selector `json:"title" yaml:"title"`
+	AltTitles   Selector `json:"alt_titles,omitempty" yaml:"alt_titles,omitempty"`
+	Series      Selector `json:"series,omitempty" yaml:"series,omitempty"`
+	Authors     Selector `json:"authors,omitempty" yaml:"authors,omitempty"`
+	Translators Selector `json:"translators,omitempty" yaml:"translators,omitempty"`
+	Tags        Selector `json:"tags,omitempty" yaml:"tags,omitempty"`
+}
+
+// RuleImages описывает, где на странице читалки лежат изображения:
+// JS-массив вида "key": [...] и/или селекторы, пробуемые по порядку.
+type RuleImages struct {
+	ScriptKey string     `json:"script_key,omitempty" yaml:"script_key,omitempty"`
+	Selectors []Selector `json:"selectors,omitempty" yaml:"selectors,omitempty"`
+}
+
+// RuleParser — SourceParser, построенный по Rule.
+type RuleParser struct {
+	rule Rule
+	path *regexp.Regexp
+	slug *regexp.Regexp
+
+	// Fetcher для загрузки страниц; nil — DefaultFetcher().
+	Fetcher *Fetcher
+}
+
+// NewRuleParser проверяет правило и компилирует его регулярные выражения.
+func NewRuleParser(r Rule) (*RuleParser, error) {
+	if strings.TrimSpace(r.Name) == "" {
+		return nil, fmt.Errorf("правило без name")
+	}
+	if len(r.Hosts) == 0 {
+		return nil, fmt.Errorf("правило %s: не заданы hosts", r.Name)
+	}
+	if r.Fields.Title.CSS == "" {
+		return nil, fmt.Errorf("правило %s: не задан fields.title", r.Name)
+	}
+	if r.Images.ScriptKey == "" && len(r.Images.Selectors) == 0 {
+		return nil, fmt.Errorf("правило %s: не задан источник изображений", r.Name)
+	}
+	p := &RuleParser{rule: r}
+	var err error
+	if r.PathPattern != "" {
+		if p.path, err = regexp.Compile(r.PathPattern); err != nil {
+			return nil, fmt.Errorf("правило %s: path_pattern: %v", r.Name, err)
+		}
+	}
+	if r.URLs.Slug != "" {
+		if p.slug, err = regexp.Compile(r.URLs.Slug); err != nil {
+			return nil, fmt.Errorf("правило %s: urls.slug: %v", r.Name, err)
+		}
+		if p.slug.NumSubexp() < 1 {
+			return nil, fmt.Errorf("правило %s: urls.slug должен содержать группу", r.Name)
+		}
+	}
+	return p, nil
+}
+
+func (p *RuleParser) Name() string    { return p.rule.Name }
+func (p *RuleParser) Hosts() []string { return p.rule.Hosts }
+
+func (p *RuleParser) Match(u *neturl.URL) bool {
+	if !hostMatches(u, p.rule.Hosts) {
+		return false
+	}
+	return p.path == nil || p.path.MatchString(u.Path)
+}
+
+func (p *RuleParser) Parse(ctx context.Context, url string) (*ParsedWork, error) {
+	infoURL, readerURL, slug, err := p.deriveURLs(url)
+	if err != nil {
+		return nil, err
+	}
+	f := p.Fetcher
+	if f == nil {
+		f = DefaultFetcher()
+	}
+	infoHTML, err := f.GetString(ctx, infoURL)
+	if err != nil {
+		return nil, err
+	}
+	readerHTML := infoHTML
+	if readerURL != infoURL {
+		if readerHTML, err = f.GetString(ctx, readerURL); err != nil {
+			return nil, err
+		}
+	}
+	w, err := p.extract(infoURL, readerURL, infoHTML, readerHTML)
+	if err != nil {
+		return nil, err
+	}
+	w.SourceID = slug
+	return w, nil
+}
+
+// deriveURLs строит ссылки на страницу информации и читалку по шаблонам правила.
+func (p *RuleParser) deriveURLs(input string) (string, string, string, error) {
+	u, err := neturl.Parse(strings.TrimSpace(input))
+	if err != nil {
+		return "", "", "", fmt.Errorf("некорректный URL: %v", err)
+	}
+	slug := u.Path[strings.LastIndex(u.Path, "/")+1:]
+	if p.slug != nil {
+		m := p.slug.FindStringSubmatch(u.Path)
+		if len(m) < 2 || m[1] == "" {
+			return "", "", "", fmt.Errorf("правило %s: не удалось выделить slug из %s", p.rule.Name, u.Path)
+		}
+		slug = m[1]
+	}
+	r := strings.NewReplacer("{scheme}", u.Scheme, "{host}", u.Host, "{path}", u.Path, "{slug}", slug)
+	info := u.String()
+	if p.rule.URLs.Info != "" {
+		info = r.Replace(p.rule.URLs.Info)
+	}
+	reader := info
+	if p.rule.URLs.Reader != "" {
+		reader = r.Replace(p.rule.URLs.Reader)
+	}
+	return info, reader, slug, nil
+}
+
+func (p *RuleParser) extract(infoURL, readerURL, infoHTML, readerHTML string) (*ParsedWork, error) {
+	infoDoc, err := parseDocument(infoHTML)
+	if err != nil {
+		return nil, fmt.Errorf("разбор страницы: %v", err)
+	}
+	readerDoc, err := parseDocument(readerHTML)
+	if err != nil {
+		return nil, fmt.Errorf("разбор страницы читалки: %v", err)
+	}
+	info := infoDoc.Selection
+	f := p.rule.Fields
+
+	var imgs []string
+	if p.rule.Images.ScriptKey != "" {
+		imgs = extractScriptArray(readerHTML, p.rule.Images.ScriptKey)
+	}
+	if len(imgs) == 0 {
+		imgs = firstNonEmpty(readerDoc.Selection, p.rule.Images.Selectors)
+	}
+	imgs = normalizeURLs(readerURL, imgs)
+
+	return &ParsedWork{
+		Source:      p.rule.Name,
+		SourceURL:   infoURL,
+		Title:       f.Title.First(info),
+		AltTitles:   dedupe(f.AltTitles.All(info)),
+		Series:      f.Series.First(info),
+		Authors:     dedupe(f.Authors.All(info)),
+		Translators: dedupe(f.Translators.All(info)),
+		Tags:        dedupe(f.Tags.All(info)),
+		Language:    p.rule.Language,
+		Pages:       NewPages(imgs),
+	}, nil
+}
+
+// LoadRule читает правило из файла .yaml/.yml/.json.
+func LoadRule(path string) (*RuleParser, error) {
+	var r Rule
+	if err := decodeFile(path, &r); err != nil {
+		return nil, err
+	}
+	p, err := NewRuleParser(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return p, nil
+}
+
+// decodeFile разбирает YAML- или JSON-файл (по расширению) в v. Поля, которых
+// нет в файле, сохраняют прежние значения v.
+func decodeFile(path string, v any) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("чтение %s: %v", path, err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(b, v)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, v)
+	default:
+		return fmt.Errorf("неизвестный формат файла: %s", path)
+	}
+	if err != nil {
+		return fmt.Errorf("разбор %s: %v", path, err)
+	}
+	return nil
+}
+
+// LoadRulesDir загружает все правила из каталога (в алфавитном порядке файлов).
+func LoadRulesDir(dir string) ([]*RuleParser, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("чтение каталога правил: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		switch strings.ToLower(filepath.Ext(e.Name())) {
+		case ".yaml", ".yml", ".json":
+			if !e.IsDir() {
+				names = append(names, e.Name())
+			}
+		}
+	}
+	sort.Strings(names)
+	out := make([]*RuleParser, 0, len(names))
+	for _, n := range names {
+		p, err := LoadRule(filepath.Join(dir, n))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// RegisterRulesDir загружает правила из каталога и регистрирует их в Default.
+// Пустой dir — ничего не делает.
+func RegisterRulesDir(dir string) ([]*RuleParser, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	ps, err := LoadRulesDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range ps {
+		Register(p)
+	}
+	return ps, nil
+}
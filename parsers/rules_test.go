@@ -0,0 +1,58 @@
+package parsers
+
+import (
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRuleParser(t *testing.T) {
+	p, err := LoadRule(filepath.Join("testdata", "rules", "hchan-like.yaml"))
+	if err != nil {
+		t.Fatalf("load rule: %v", err)
+	}
+
+	u, _ := url.Parse("https://www.h-chan.example/online/46143-letnie-kanikuly.html")
+	if !p.Match(u) {
+		t.Fatalf("rule should match %s", u)
+	}
+	other, _ := url.Parse("https://h-chan.example/news/1")
+	if p.Match(other) {
+		t.Fatalf("rule should not match %s", other)
+	}
+
+	info, reader, slug, err := p.deriveURLs(u.String())
+	if err != nil {
+		t.Fatalf("derive urls: %v", err)
+	}
+	if info != "https://www.h-chan.example/manga/46143-letnie-kanikuly.html" || reader != "https://www.h-chan.example/online/46143-letnie-kanikuly.html" {
+		t.Errorf("derived urls = %s, %s", info, reader)
+	}
+	if slug != "46143-letnie-kanikuly.html" {
+		t.Errorf("slug = %q", slug)
+	}
+
+	w, err := p.extract(info, reader, readFixture(t, "manga.html"), readFixture(t, "online.html"))
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if w.Source != "hchan-like" || w.Language != "ru" || w.Title != "Летние каникулы" || w.Series != "Оригинальные работы" {
+		t.Errorf("unexpected meta: %+v", w)
+	}
+	if want := []string{"Tanaka", "Suzuki"}; !reflect.DeepEqual(w.Authors, want) {
+		t.Errorf("authors = %q, want %q", w.Authors, want)
+	}
+	if want := []string{"большая грудь", "ванилла", "школьная форма"}; !reflect.DeepEqual(w.Tags, want) {
+		t.Errorf("tags = %q, want %q", w.Tags, want)
+	}
+	if len(w.Pages) != 3 || w.Pages[2].URL != "https://img4.h-chan.me/manganew/l/letnie/03.png" {
+		t.Errorf("pages = %+v", w.Pages)
+	}
+}
+
+func TestLoadRulesDirRejectsInvalid(t *testing.T) {
+	if _, err := LoadRulesDir(filepath.Join("testdata", "rules")); err == nil {
+		t.Fatal("expected error for rule without images")
+	}
+}
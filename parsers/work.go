@@ -0,0 +1,90 @@
+package parsers
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParsedWork — источник-независимое описание работы, которое возвращает любой парсер.
+type ParsedWork struct {
+	Source      string // имя парсера-источника, см. SourceParser.Name()
+	SourceID    string // идентификатор работы на сайте-источнике (slug, id)
+	SourceURL   string // исходная ссылка
+	Title       string
+	AltTitles   []string
+	Series      string
+	Authors     []string
+	Translators []string
+	Tags        []string
+	Language    string // код языка (ISO 639-1), например "ru"
+	Pages       []Page // страницы в порядке чтения
+}
+
+// Page — одна страница (изображение) работы.
+type Page struct {
+	Index int    `json:"index"`
+	URL   string `json:"url"`
+}
+
+// PageURLs возвращает ссылки на изображения в порядке страниц.
+func (w *ParsedWork) PageURLs() []string {
+	out := make([]string, 0, len(w.Pages))
+	for _, p := range w.Pages {
+		out = append(out, p.URL)
+	}
+	return out
+}
+
+// NewPages строит упорядоченный список страниц из ссылок на изображения.
+func NewPages(urls []string) []Page {
+	pages := make([]Page, 0, len(urls))
+	for i, u := range urls {
+		pages = append(pages, Page{Index: i + 1, URL: u})
+	}
+	return pages
+}
+
+// PagesDiff — отличия нового списка страниц от сохранённого.
+type PagesDiff struct {
+	Added   int // новые номера страниц
+	Removed int // пропавшие номера страниц
+	Changed int // тот же номер, другое изображение
+}
+
+func (d PagesDiff) Empty() bool { return d.Added == 0 && d.Removed == 0 && d.Changed == 0 }
+
+func (d PagesDiff) String() string {
+	return fmt.Sprintf("+%d -%d ~%d", d.Added, d.Removed, d.Changed)
+}
+
+// DiffPages сравнивает страницы по номеру.
+func DiffPages(old, new []Page) PagesDiff {
+	prev := make(map[int]string, len(old))
+	for _, p := range old {
+		prev[p.Index] = p.URL
+	}
+	var d PagesDiff
+	for _, p := range new {
+		u, ok := prev[p.Index]
+		switch {
+		case !ok:
+			d.Added++
+		case u != p.URL:
+			d.Changed++
+		}
+		delete(prev, p.Index)
+	}
+	d.Removed = len(prev)
+	return d
+}
+
+// splitNames разбивает поле вида "A, B" на отдельные имена.
+func splitNames(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
@@ -0,0 +1,18 @@
+package parsers
+
+import "testing"
+
+func TestDiffPages(t *testing.T) {
+	old := NewPages([]string{"a", "b", "c"})
+	if d := DiffPages(old, NewPages([]string{"a", "b", "c"})); !d.Empty() {
+		t.Errorf("same pages: %v", d)
+	}
+	d := DiffPages(old, NewPages([]string{"a", "x", "c", "d", "e"}))
+	if d != (PagesDiff{Added: 2, Changed: 1}) {
+		t.Errorf("extended: %v", d)
+	}
+	d = DiffPages(old, NewPages([]string{"a"}))
+	if d != (PagesDiff{Removed: 2}) {
+		t.Errorf("shortened: %v", d)
+	}
+}
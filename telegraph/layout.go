@@ -0,0 +1,112 @@
+package telegraph
+
+import (
+	"net/url"
+	"strings"
+)
+
+// PageMeta — данные работы для оформления страницы.
+type PageMeta struct {
+	Title       string
+	AltTitles   []string
+	Series      string
+	Authors     []string
+	Translators []string
+	Tags        []string
+	SourceName  string // подпись ссылки на источник; пусто — хост SourceURL
+	SourceURL   string
+
+	// TagURL — шаблон ссылки на тег с подстановкой {tag}, например
+	// https://t.me/s/channel?q=%23{tag}. Пусто — теги без ссылок.
+	TagURL        string
+	SubscribeURL  string // ссылка в подвале; пусто — без подвала
+	SubscribeText string // текст ссылки подписки; пусто — "канал"
+}
+
+// BuildArticle оформляет страницу работы: первое изображение — обложка
+// с подписью, под ней названия, серия, авторы, переводчики, теги и источник,
+// затем остальные изображения и подвал со ссылкой на канал.
+func BuildArticle(m PageMeta, imageURLs []string) Article {
+	a := Article{Title: m.Title, Images: imageURLs}
+	if len(imageURLs) > 0 {
+		a.Header = append(a.Header, Elem("figure", Image(imageURLs[0]), Elem("figcaption", Text(m.Title))))
+		a.Images = imageURLs[1:]
+	}
+	if len(m.AltTitles) > 0 {
+		a.Header = append(a.Header, Elem("h4", Text(strings.Join(m.AltTitles, " / "))))
+	}
+
+	var info []Node
+	field := func(label string, value ...Node) {
+		if len(info) > 0 {
+			info = append(info, Elem("br"))
+		}
+		info = append(info, Elem("strong", Text(label+": ")))
+		info = append(info, value...)
+	}
+	if m.Series != "" {
+		field("Серия", Text(m.Series))
+	}
+	if len(m.Authors) > 0 {
+		field("Автор", Text(strings.Join(m.Authors, ", ")))
+	}
+	if len(m.Translators) > 0 {
+		field("Переводчик", Text(strings.Join(m.Translators, ", ")))
+	}
+	if len(m.Tags) > 0 {
+		field("Теги", tagNodes(m.Tags, m.TagURL)...)
+	}
+	if m.SourceURL != "" {
+		name := m.SourceName
+		if name == "" {
+			name = hostOf(m.SourceURL)
+		}
+		field("Источник", Link(name, m.SourceURL))
+	}
+	if len(info) > 0 {
+		a.Header = append(a.Header, Elem("p", info...))
+	}
+	if len(a.Header) > 0 {
+		a.Header = append(a.Header, Elem("hr"))
+	}
+
+	if m.SubscribeURL != "" {
+		text := m.SubscribeText
+		if text == "" {
+			text = "канал"
+		}
+		a.Footer = []Node{Elem("hr"), Elem("p", Text("Подписывайся: "), Link(text, m.SubscribeURL))}
+	}
+	return a
+}
+
+// tagNodes выводит теги через запятую в виде #тег, ссылками, если задан шаблон.
+func tagNodes(tags []string, tmpl string) []Node {
+	var out []Node
+	for i, t := range tags {
+		if i > 0 {
+			out = append(out, Text(", "))
+		}
+		tag := normalizeTag(t)
+		if tmpl == "" {
+			out = append(out, Text("#"+tag))
+			continue
+		}
+		out = append(out, Link("#"+tag, strings.ReplaceAll(tmpl, "{tag}", url.QueryEscape(tag))))
+	}
+	return out
+}
+
+// normalizeTag — как хештеги в посте канала (scheduler.normalizeTagString):
+// нижний регистр, пробелы и дефисы — "_".
+func normalizeTag(s string) string {
+	s = strings.ReplaceAll(strings.ToLower(s), "-", "_")
+	return strings.Join(strings.Fields(s), "_")
+}
+
+func hostOf(raw string) string {
+	if u, err := url.Parse(raw); err == nil && u.Host != "" {
+		return strings.TrimPrefix(u.Host, "www.")
+	}
+	return raw
+}
@@ -0,0 +1,52 @@
+package telegraph
+
+import "encoding/json"
+
+// Node — элемент DOM страницы Telegraph: либо текст (Tag пуст), либо
+// тег с атрибутами и дочерними узлами.
+type Node struct {
+	Text     string
+	Tag      string
+	Attrs    map[string]string
+	Children []Node
+}
+
+type nodeElement struct {
+	Tag      string            `json:"tag"`
+	Attrs    map[string]string `json:"attrs,omitempty"`
+	Children []Node            `json:"children,omitempty"`
+}
+
+func (n Node) MarshalJSON() ([]byte, error) {
+	if n.Tag == "" {
+		return json.Marshal(n.Text)
+	}
+	return json.Marshal(nodeElement{Tag: n.Tag, Attrs: n.Attrs, Children: n.Children})
+}
+
+func (n *Node) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		*n = Node{}
+		return json.Unmarshal(b, &n.Text)
+	}
+	var e nodeElement
+	if err := json.Unmarshal(b, &e); err != nil {
+		return err
+	}
+	*n = Node{Tag: e.Tag, Attrs: e.Attrs, Children: e.Children}
+	return nil
+}
+
+// Text создаёт текстовый узел.
+func Text(s string) Node { return Node{Text: s} }
+
+// Elem создаёт узел-тег с дочерними узлами.
+func Elem(tag string, children ...Node) Node { return Node{Tag: tag, Children: children} }
+
+// Image создаёт узел <img src>.
+func Image(src string) Node { return Node{Tag: "img", Attrs: map[string]string{"src": src}} }
+
+// Link создаёт ссылку <a href> с текстом.
+func Link(text, href string) Node {
+	return Node{Tag: "a", Attrs: map[string]string{"href": href}, Children: []Node{Text(text)}}
+}
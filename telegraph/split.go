@@ -0,0 +1,219 @@
+package telegraph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	appErr "go_scripts/internal/errors"
+)
+
+// SplitOptions — ограничения одной страницы Telegraph. Telegraph отклоняет
+// content больше ~64 КБ, а страницы с сотнями изображений грузятся плохо.
+type SplitOptions struct {
+	MaxImages int // изображений на страницу
+	MaxBytes  int // размер JSON content на страницу
+}
+
+func DefaultSplitOptions() SplitOptions {
+	return SplitOptions{MaxImages: 100, MaxBytes: 60000}
+}
+
+// navReserve — запас байт под навигацию «предыдущая/следующая часть».
+const navReserve = 1024
+
+// Article — публикуемая работа: шапка выводится над изображениями первой
+// части, подвал — под последней. Простая страница — только Title и Images.
+type Article struct {
+	Title  string
+	Header []Node
+	Images []string
+	Footer []Node
+}
+
+// reserve — байты, которые кроме изображений может занять одна часть.
+func (a Article) reserve() int {
+	h, _ := json.Marshal(a.Header)
+	f, _ := json.Marshal(a.Footer)
+	return navReserve + len(h) + len(f)
+}
+
+// splitImages делит изображения на части, каждая из которых вместе с reserve
+// байтами служебного содержимого укладывается в opts. Частей всегда не меньше одной.
+func splitImages(imageURLs []string, opts SplitOptions, reserve int) [][]string {
+	if opts.MaxImages <= 0 {
+		opts.MaxImages = DefaultSplitOptions().MaxImages
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultSplitOptions().MaxBytes
+	}
+	var parts [][]string
+	var cur []string
+	size := 2 // []
+	for _, u := range imageURLs {
+		b, _ := json.Marshal(Image(u))
+		n := len(b) + 1
+		if len(cur) > 0 && (len(cur) >= opts.MaxImages || size+n+reserve > opts.MaxBytes) {
+			parts = append(parts, cur)
+			cur, size = nil, 2
+		}
+		cur = append(cur, u)
+		size += n
+	}
+	if len(cur) > 0 || len(parts) == 0 {
+		parts = append(parts, cur)
+	}
+	return parts
+}
+
+// CreateImagePages создаёт страницу из изображений, при необходимости разбивая
+// её на пронумерованные части со ссылками «предыдущая/следующая часть».
+// Возвращает URL частей по порядку; первая — основная ссылка на работу.
+func (c *Client) CreateImagePages(ctx context.Context, title string, imageURLs []string, opts SplitOptions) ([]string, error) {
+	return c.CreateArticle(ctx, Article{Title: title, Images: imageURLs}, opts)
+}
+
+// CreateArticle — CreateImagePages с шапкой и подвалом. Если часть создать
+// не удалось, вместе с ошибкой возвращаются URL уже созданных частей, чтобы
+// вызывающий мог их учесть: удалить страницу Telegraph нельзя.
+func (c *Client) CreateArticle(ctx context.Context, a Article, opts SplitOptions) ([]string, error) {
+	parts := splitImages(a.Images, opts, a.reserve())
+	total := len(parts)
+
+	// Ссылка на следующую часть появляется только после её создания, поэтому
+	// части создаются со ссылкой назад, а затем дописываются ссылки вперёд.
+	pages := make([]*Page, 0, total)
+	for i, imgs := range parts {
+		prev := ""
+		if i > 0 {
+			prev = pages[i-1].URL
+		}
+		p, err := c.CreatePage(ctx, partTitle(a.Title, i, total), partContent(a, imgs, i, total, prev, ""), false)
+		if err != nil {
+			if total == 1 {
+				return nil, err
+			}
+			return pageURLs(pages), fmt.Errorf("part %d/%d: %w", i+1, total, err)
+		}
+		pages = append(pages, p)
+	}
+	for i := 0; i < total-1; i++ {
+		prev := ""
+		if i > 0 {
+			prev = pages[i-1].URL
+		}
+		content := partContent(a, parts[i], i, total, prev, pages[i+1].URL)
+		if _, err := c.EditPage(ctx, pages[i].Path, partTitle(a.Title, i, total), content, false); err != nil {
+			return pageURLs(pages), fmt.Errorf("link part %d/%d: %w", i+1, total, err)
+		}
+	}
+	return pageURLs(pages), nil
+}
+
+func pageURLs(pages []*Page) []string {
+	urls := make([]string, len(pages))
+	for i, p := range pages {
+		urls[i] = p.URL
+	}
+	return urls
+}
+
+// EditImagePages обновляет опубликованные части pageURLs новым списком
+// изображений. Существующие страницы редактируются на месте, поэтому ссылки
+// на них (пост в канале ведёт на первую часть) остаются рабочими; недостающие
+// части создаются, а лишние заменяются ссылкой на первую часть.
+func (c *Client) EditImagePages(ctx context.Context, title string, pageURLs, imageURLs []string, opts SplitOptions) ([]string, error) {
+	return c.EditArticle(ctx, pageURLs, Article{Title: title, Images: imageURLs}, opts)
+}
+
+// EditArticle — EditImagePages с шапкой и подвалом. При ошибке вместе с ней
+// возвращаются URL частей, известных к этому моменту: сначала pageURLs, затем
+// созданные в этом вызове, — чтобы вызывающий мог учесть новые страницы.
+func (c *Client) EditArticle(ctx context.Context, pageURLs []string, a Article, opts SplitOptions) ([]string, error) {
+	if len(pageURLs) == 0 {
+		return nil, appErr.NewInternalError("edit image pages: no existing pages", nil)
+	}
+	parts := splitImages(a.Images, opts, a.reserve())
+	total := len(parts)
+	urls := make([]string, total)
+	paths := make([]string, total)
+	for i := range parts {
+		if i < len(pageURLs) {
+			urls[i], paths[i] = pageURLs[i], PagePath(pageURLs[i])
+			continue
+		}
+		p, err := c.CreatePage(ctx, partTitle(a.Title, i, total), partContent(a, parts[i], i, total, urls[i-1], ""), false)
+		if err != nil {
+			return urls[:i], fmt.Errorf("part %d/%d: %w", i+1, total, err)
+		}
+		urls[i], paths[i] = p.URL, p.Path
+	}
+	for i := range parts {
+		if i >= len(pageURLs) && i == total-1 {
+			continue // только что создана с актуальной навигацией
+		}
+		prev, next := "", ""
+		if i > 0 {
+			prev = urls[i-1]
+		}
+		if i < total-1 {
+			next = urls[i+1]
+		}
+		if _, err := c.EditPage(ctx, paths[i], partTitle(a.Title, i, total), partContent(a, parts[i], i, total, prev, next), false); err != nil {
+			return urls, fmt.Errorf("edit part %d/%d: %w", i+1, total, err)
+		}
+	}
+	for _, u := range pageURLs[min(total, len(pageURLs)):] {
+		stub := []Node{Elem("p", Text("Глава обновлена и стала короче — читайте её с "), Link("первой части", urls[0]), Text("."))}
+		if _, err := c.EditPage(ctx, PagePath(u), a.Title, stub, false); err != nil {
+			return urls, fmt.Errorf("edit stale part %s: %w", u, err)
+		}
+	}
+	return urls, nil
+}
+
+// PagePath возвращает path страницы (для editPage/getPage) по её URL.
+func PagePath(pageURL string) string {
+	if u, err := url.Parse(pageURL); err == nil && u.Host != "" {
+		return strings.TrimPrefix(u.Path, "/")
+	}
+	return strings.TrimPrefix(pageURL, "/")
+}
+
+func partTitle(title string, i, total int) string {
+	if total <= 1 {
+		return title
+	}
+	return fmt.Sprintf("%s (часть %d/%d)", title, i+1, total)
+}
+
+// partContent — шапка (в первой части), изображения части, навигация под
+// ними и подвал (в последней части).
+func partContent(a Article, imgs []string, i, total int, prevURL, nextURL string) []Node {
+	content := make([]Node, 0, len(imgs)+len(a.Header)+len(a.Footer)+1)
+	if i == 0 {
+		content = append(content, a.Header...)
+	}
+	for _, u := range imgs {
+		content = append(content, Image(u))
+	}
+	var nav []Node
+	if prevURL != "" {
+		nav = append(nav, Link(fmt.Sprintf("← Часть %d", i), prevURL))
+	}
+	if nextURL != "" {
+		if len(nav) > 0 {
+			nav = append(nav, Text(" · "))
+		}
+		nav = append(nav, Link(fmt.Sprintf("Часть %d →", i+2), nextURL))
+	}
+	if len(nav) > 0 {
+		content = append(content, Elem("p", nav...))
+	}
+	if i == total-1 {
+		content = append(content, a.Footer...)
+	}
+	return content
+}
@@ -1,65 +1,337 @@
 package telegraph
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
-	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	appErr "go_scripts/internal/errors"
 )
 
+const DefaultBaseURL = "https://api.telegra.ph"
+
+// Config — настройки клиента Telegraph.
+type Config struct {
+	BaseURL     string // по умолчанию DefaultBaseURL
+	UploadURL   string // по умолчанию DefaultUploadURL
+	AccessToken string
+	AuthorName  string // подставляется в createPage/editPage
+	AuthorURL   string
+	Timeout     time.Duration
+}
+
+// Client — клиент Telegraph API (https://telegra.ph/api).
+type Client struct {
+	baseURL    string
+	uploadURL  string
+	httpClient *http.Client
+
+	mu          sync.RWMutex
+	accessToken string
+	authorName  string
+	authorURL   string
+}
+
+func NewClient(c Config) *Client {
+	if c.BaseURL == "" {
+		c.BaseURL = DefaultBaseURL
+	}
+	if c.UploadURL == "" {
+		c.UploadURL = DefaultUploadURL
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 30 * time.Second
+	}
+	return &Client{
+		baseURL:     strings.TrimRight(c.BaseURL, "/"),
+		uploadURL:   c.UploadURL,
+		httpClient:  &http.Client{Timeout: c.Timeout},
+		accessToken: c.AccessToken,
+		authorName:  c.AuthorName,
+		authorURL:   c.AuthorURL,
+	}
+}
+
+// AccessToken возвращает текущий токен (меняется после RevokeAccessToken).
+func (c *Client) AccessToken() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.accessToken
+}
+
+// SetAccessToken переключает клиент на другой аккаунт.
+func (c *Client) SetAccessToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.accessToken = token
+}
+
+// WithAccount возвращает клиент другого аккаунта с тем же адресом API и
+// HTTP-клиентом. Пустые authorName/authorURL берутся из c.
+func (c *Client) WithAccount(token, authorName, authorURL string) *Client {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if authorName == "" {
+		authorName = c.authorName
+	}
+	if authorURL == "" {
+		authorURL = c.authorURL
+	}
+	return &Client{
+		baseURL:     c.baseURL,
+		uploadURL:   c.uploadURL,
+		httpClient:  c.httpClient,
+		accessToken: token,
+		authorName:  authorName,
+		authorURL:   authorURL,
+	}
+}
+
 // Структуры для обработки ответа API
-type PageResponse struct {
-	Ok     bool       `json:"ok"`
-	Result PageResult `json:"result"`
-	Error  string     `json:"error,omitempty"`
+type response struct {
+	Ok     bool            `json:"ok"`
+	Result json.RawMessage `json:"result"`
+	Error  string          `json:"error,omitempty"`
 }
 
-type PageResult struct {
-	URL string `json:"url"`
+type Account struct {
+	ShortName   string `json:"short_name"`
+	AuthorName  string `json:"author_name"`
+	AuthorURL   string `json:"author_url"`
+	AccessToken string `json:"access_token,omitempty"`
+	AuthURL     string `json:"auth_url,omitempty"`
+	PageCount   int    `json:"page_count,omitempty"`
 }
 
-type Node struct {
-	Tag   string            `json:"tag,omitempty"`
-	Attrs map[string]string `json:"attrs,omitempty"`
+type Page struct {
+	Path        string `json:"path"`
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	AuthorName  string `json:"author_name,omitempty"`
+	AuthorURL   string `json:"author_url,omitempty"`
+	ImageURL    string `json:"image_url,omitempty"`
+	Content     []Node `json:"content,omitempty"`
+	Views       int    `json:"views"`
+	CanEdit     bool   `json:"can_edit,omitempty"`
 }
 
-func CreateTelegraphPage(title string, imageURLs []string) (string, error) {
-	accessToken := os.Getenv("ACCESS_TOKEN")
-	authorName := os.Getenv("AUTHOR_NAME")
-	authorURL := os.Getenv("AUTHOR_URL")
+type PageList struct {
+	TotalCount int    `json:"total_count"`
+	Pages      []Page `json:"pages"`
+}
 
+type PageViews struct {
+	Views int `json:"views"`
+}
+
+// call выполняет метод API и раскладывает result в out.
+func (c *Client) call(ctx context.Context, method string, params url.Values, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/"+method, strings.NewReader(params.Encode()))
+	if err != nil {
+		return appErr.NewInternalError("telegraph request", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return appErr.NewNetworkError("telegraph request", err).WithTransient(true)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return appErr.NewNetworkError("telegraph server error", fmt.Errorf("%s", resp.Status)).WithTransient(true)
+	}
+
+	var r response
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return appErr.NewNetworkError("telegraph decode", err).WithTransient(true)
+	}
+	if !r.Ok {
+		return apiError(r.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(r.Result, out); err != nil {
+		return appErr.NewTelegramError("telegraph decode result", err)
+	}
+	return nil
+}
+
+// apiError классифицирует ошибку Telegraph API: FLOOD_WAIT_N — временная
+// с паузой N секунд, остальные (CONTENT_TOO_BIG, ...) — постоянные.
+func apiError(code string) *appErr.AppError {
+	e := appErr.NewTelegramError("telegraph API error: "+code, nil).WithCode(code)
+	if rest, ok := strings.CutPrefix(code, "FLOOD_WAIT_"); ok {
+		secs, _ := strconv.Atoi(rest)
+		return e.WithRetryAfter(time.Duration(secs) * time.Second)
+	}
+	return e
+}
+
+// CreateAccount создаёт новый аккаунт. Клиент не переключается на него
+// автоматически — используйте SetAccessToken.
+func (c *Client) CreateAccount(ctx context.Context, shortName, authorName, authorURL string) (*Account, error) {
+	var acc Account
+	if err := c.call(ctx, "createAccount", url.Values{
+		"short_name":  {shortName},
+		"author_name": {authorName},
+		"author_url":  {authorURL},
+	}, &acc); err != nil {
+		return nil, err
+	}
+	return &acc, nil
+}
+
+// EditAccountInfo меняет данные аккаунта; пустые поля не отправляются.
+func (c *Client) EditAccountInfo(ctx context.Context, shortName, authorName, authorURL string) (*Account, error) {
+	params := url.Values{"access_token": {c.AccessToken()}}
+	setIf(params, "short_name", shortName)
+	setIf(params, "author_name", authorName)
+	setIf(params, "author_url", authorURL)
+	var acc Account
+	if err := c.call(ctx, "editAccountInfo", params, &acc); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.authorName, c.authorURL = acc.AuthorName, acc.AuthorURL
+	c.mu.Unlock()
+	return &acc, nil
+}
+
+// GetAccountInfo возвращает данные аккаунта, включая page_count и auth_url.
+func (c *Client) GetAccountInfo(ctx context.Context) (*Account, error) {
+	var acc Account
+	if err := c.call(ctx, "getAccountInfo", url.Values{
+		"access_token": {c.AccessToken()},
+		"fields":       {`["short_name","author_name","author_url","auth_url","page_count"]`},
+	}, &acc); err != nil {
+		return nil, err
+	}
+	return &acc, nil
+}
+
+// RevokeAccessToken отзывает текущий токен; клиент переключается на новый.
+func (c *Client) RevokeAccessToken(ctx context.Context) (*Account, error) {
+	var acc Account
+	if err := c.call(ctx, "revokeAccessToken", url.Values{"access_token": {c.AccessToken()}}, &acc); err != nil {
+		return nil, err
+	}
+	c.SetAccessToken(acc.AccessToken)
+	return &acc, nil
+}
+
+func (c *Client) CreatePage(ctx context.Context, title string, content []Node, returnContent bool) (*Page, error) {
+	params, err := c.pageParams(title, content, returnContent)
+	if err != nil {
+		return nil, err
+	}
+	var p Page
+	if err := c.call(ctx, "createPage", params, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// EditPage заменяет содержимое существующей страницы path.
+func (c *Client) EditPage(ctx context.Context, path, title string, content []Node, returnContent bool) (*Page, error) {
+	params, err := c.pageParams(title, content, returnContent)
+	if err != nil {
+		return nil, err
+	}
+	var p Page
+	if err := c.call(ctx, "editPage/"+url.PathEscape(path), params, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (c *Client) GetPage(ctx context.Context, path string, returnContent bool) (*Page, error) {
+	var p Page
+	err := c.call(ctx, "getPage/"+url.PathEscape(path), url.Values{
+		"return_content": {strconv.FormatBool(returnContent)},
+	}, &p)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetPageList возвращает страницы аккаунта, начиная с самых новых.
+func (c *Client) GetPageList(ctx context.Context, offset, limit int) (*PageList, error) {
+	var pl PageList
+	err := c.call(ctx, "getPageList", url.Values{
+		"access_token": {c.AccessToken()},
+		"offset":       {strconv.Itoa(offset)},
+		"limit":        {strconv.Itoa(limit)},
+	}, &pl)
+	if err != nil {
+		return nil, err
+	}
+	return &pl, nil
+}
+
+// GetViews возвращает число просмотров страницы. Нулевые year/month/day/hour
+// не передаются, т.е. без них — за всё время.
+func (c *Client) GetViews(ctx context.Context, path string, year, month, day, hour int) (int, error) {
+	params := url.Values{}
+	setIf(params, "year", itoaNonZero(year))
+	setIf(params, "month", itoaNonZero(month))
+	setIf(params, "day", itoaNonZero(day))
+	if hour > 0 {
+		params.Set("hour", strconv.Itoa(hour))
+	}
+	var v PageViews
+	if err := c.call(ctx, "getViews/"+url.PathEscape(path), params, &v); err != nil {
+		return 0, err
+	}
+	return v.Views, nil
+}
+
+// CreateImagePage создаёт страницу из списка изображений и возвращает её URL.
+func (c *Client) CreateImagePage(ctx context.Context, title string, imageURLs []string) (string, error) {
 	content := make([]Node, 0, len(imageURLs))
 	for _, u := range imageURLs {
-		content = append(content, Node{Tag: "img", Attrs: map[string]string{"src": u}})
+		content = append(content, Image(u))
+	}
+	p, err := c.CreatePage(ctx, title, content, false)
+	if err != nil {
+		return "", err
 	}
+	return p.URL, nil
+}
 
+func (c *Client) pageParams(title string, content []Node, returnContent bool) (url.Values, error) {
 	body, err := json.Marshal(content)
 	if err != nil {
-		return "", fmt.Errorf("marshal content: %w", err)
+		return nil, appErr.NewInternalError("marshal content", err)
 	}
-
-	params := url.Values{
-		"access_token":   {accessToken},
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return url.Values{
+		"access_token":   {c.accessToken},
 		"title":          {title},
-		"author_name":    {authorName},
-		"author_url":     {authorURL},
+		"author_name":    {c.authorName},
+		"author_url":     {c.authorURL},
 		"content":        {string(body)},
-		"return_content": {"false"},
-	}
+		"return_content": {strconv.FormatBool(returnContent)},
+	}, nil
+}
 
-	resp, err := http.PostForm("https://api.telegra.ph/createPage", params)
-	if err != nil {
-		return "", fmt.Errorf("request: %w", err)
+func setIf(v url.Values, key, value string) {
+	if value != "" {
+		v.Set(key, value)
 	}
-	defer resp.Body.Close()
+}
 
-	var r PageResponse
-	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
-		return "", fmt.Errorf("decode: %w", err)
-	}
-	if !r.Ok {
-		return "", fmt.Errorf("API error: %s", r.Error)
+func itoaNonZero(n int) string {
+	if n == 0 {
+		return ""
 	}
-	return r.Result.URL, nil
+	return strconv.Itoa(n)
 }
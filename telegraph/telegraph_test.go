@@ -0,0 +1,396 @@
+package telegraph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	appErr "go_scripts/internal/errors"
+)
+
+// fakeServer — минимальная имитация Telegraph API: отвечает заранее
+// заданными result/error по имени метода и запоминает присланные параметры.
+// result может быть func(params map[string]string) any для динамических ответов.
+type fakeServer struct {
+	*httptest.Server
+	results map[string]any
+	errors  map[string]string
+	params  map[string]map[string]string
+	calls   []map[string]string
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+	f := &fakeServer{results: map[string]any{}, errors: map[string]string{}, params: map[string]map[string]string{}}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method := strings.TrimPrefix(r.URL.Path, "/")
+		if i := strings.Index(method, "/"); i >= 0 {
+			method = method[:i]
+		}
+		_ = r.ParseForm()
+		got := map[string]string{"path": r.URL.Path}
+		for k := range r.PostForm {
+			got[k] = r.PostForm.Get(k)
+		}
+		f.params[method] = got
+		if e, ok := f.errors[method]; ok {
+			_ = json.NewEncoder(w).Encode(map[string]any{"ok": false, "error": e})
+			return
+		}
+		res := f.results[method]
+		if fn, ok := res.(func(map[string]string) any); ok {
+			res = fn(got)
+		}
+		f.calls = append(f.calls, got)
+		_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": res})
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeServer) client() *Client {
+	return NewClient(Config{BaseURL: f.URL, AccessToken: "tok", AuthorName: "Niko", AuthorURL: "https://t.me/niko"})
+}
+
+func TestCreatePageSendsContentAndAuthor(t *testing.T) {
+	f := newFakeServer(t)
+	f.results["createPage"] = Page{Path: "Title-01-01", URL: "https://telegra.ph/Title-01-01"}
+
+	url, err := f.client().CreateImagePage(context.Background(), "Title", []string{"https://a/1.jpg", "https://a/2.jpg"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if url != "https://telegra.ph/Title-01-01" {
+		t.Errorf("url = %q", url)
+	}
+	p := f.params["createPage"]
+	if p["access_token"] != "tok" || p["title"] != "Title" || p["author_name"] != "Niko" {
+		t.Errorf("params = %v", p)
+	}
+	want := `[{"tag":"img","attrs":{"src":"https://a/1.jpg"}},{"tag":"img","attrs":{"src":"https://a/2.jpg"}}]`
+	if p["content"] != want {
+		t.Errorf("content = %s", p["content"])
+	}
+}
+
+func TestGetPageDecodesNodeTree(t *testing.T) {
+	f := newFakeServer(t)
+	f.results["getPage"] = json.RawMessage(`{"path":"P","url":"u","title":"T","content":[
+		{"tag":"p","children":["Hello, ",{"tag":"a","attrs":{"href":"https://x"},"children":["link"]}]}]}`)
+
+	page, err := f.client().GetPage(context.Background(), "P", true)
+	if err != nil {
+		t.Fatalf("get page: %v", err)
+	}
+	want := []Node{Elem("p", Text("Hello, "), Node{Tag: "a", Attrs: map[string]string{"href": "https://x"}, Children: []Node{Text("link")}})}
+	if !reflect.DeepEqual(page.Content, want) {
+		t.Errorf("content = %#v", page.Content)
+	}
+	if f.params["getPage"]["path"] != "/getPage/P" {
+		t.Errorf("path = %s", f.params["getPage"]["path"])
+	}
+}
+
+func TestRevokeAccessTokenSwitchesClient(t *testing.T) {
+	f := newFakeServer(t)
+	f.results["revokeAccessToken"] = Account{ShortName: "niko", AccessToken: "new-tok"}
+	c := f.client()
+	if _, err := c.RevokeAccessToken(context.Background()); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if c.AccessToken() != "new-tok" {
+		t.Errorf("token = %q", c.AccessToken())
+	}
+}
+
+func TestAccountMethodsReturnNilOnError(t *testing.T) {
+	f := newFakeServer(t)
+	f.errors["createAccount"] = "SHORT_NAME_REQUIRED"
+	f.errors["getAccountInfo"] = "ACCESS_TOKEN_INVALID"
+	c := f.client()
+	ctx := context.Background()
+
+	if acc, err := c.CreateAccount(ctx, "", "", ""); err == nil || acc != nil {
+		t.Errorf("create account: %+v %v", acc, err)
+	}
+	if acc, err := c.GetAccountInfo(ctx); err == nil || acc != nil {
+		t.Errorf("account info: %+v %v", acc, err)
+	}
+}
+
+func TestAccountPageListAndViews(t *testing.T) {
+	f := newFakeServer(t)
+	f.results["createAccount"] = Account{ShortName: "niko", AccessToken: "t2"}
+	f.results["editAccountInfo"] = Account{ShortName: "niko", AuthorName: "New"}
+	f.results["getPageList"] = PageList{TotalCount: 1, Pages: []Page{{Path: "P", Views: 3}}}
+	f.results["getViews"] = PageViews{Views: 42}
+	c := f.client()
+	ctx := context.Background()
+
+	if acc, err := c.CreateAccount(ctx, "niko", "Niko", ""); err != nil || acc.AccessToken != "t2" {
+		t.Fatalf("create account: %+v %v", acc, err)
+	}
+	if acc, err := c.EditAccountInfo(ctx, "", "New", ""); err != nil || acc.AuthorName != "New" {
+		t.Fatalf("edit account: %+v %v", acc, err)
+	}
+	if _, ok := f.params["editAccountInfo"]["short_name"]; ok {
+		t.Errorf("empty short_name must not be sent")
+	}
+	if pl, err := c.GetPageList(ctx, 0, 10); err != nil || pl.TotalCount != 1 || pl.Pages[0].Views != 3 {
+		t.Fatalf("page list: %+v %v", pl, err)
+	}
+	if n, err := c.GetViews(ctx, "P", 2026, 10, 0, 0); err != nil || n != 42 {
+		t.Fatalf("views: %d %v", n, err)
+	}
+	if p := f.params["getViews"]; p["year"] != "2026" || p["month"] != "10" || p["day"] != "" {
+		t.Errorf("views params = %v", p)
+	}
+}
+
+func TestFloodWaitIsTransient(t *testing.T) {
+	f := newFakeServer(t)
+	f.errors["createPage"] = "FLOOD_WAIT_7"
+	_, err := f.client().CreatePage(context.Background(), "T", []Node{Text("x")}, false)
+	if !appErr.IsTransient(err) || appErr.RetryAfterOf(err) != 7*time.Second {
+		t.Fatalf("expected transient flood error with 7s, got %v", err)
+	}
+
+	f.errors["createPage"] = "CONTENT_TOO_BIG"
+	if _, err := f.client().CreatePage(context.Background(), "T", nil, false); err == nil || appErr.IsTransient(err) {
+		t.Fatalf("expected permanent error, got %v", err)
+	}
+}
+
+func TestSplitImagesRespectsLimits(t *testing.T) {
+	urls := make([]string, 250)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("https://img.example/%03d.jpg", i)
+	}
+	parts := splitImages(urls, SplitOptions{MaxImages: 100, MaxBytes: 60000}, navReserve)
+	if len(parts) != 3 || len(parts[0]) != 100 || len(parts[2]) != 50 {
+		t.Fatalf("parts sizes = %d", len(parts))
+	}
+	parts = splitImages(urls, SplitOptions{MaxImages: 1000, MaxBytes: 5000}, navReserve)
+	for i, p := range parts {
+		b, _ := json.Marshal(partContent(Article{}, p, i, len(parts), "https://telegra.ph/prev", "https://telegra.ph/next"))
+		if len(b) > 5000 {
+			t.Errorf("part %d is %d bytes", i, len(b))
+		}
+	}
+}
+
+func TestCreateImagePagesLinksParts(t *testing.T) {
+	f := newFakeServer(t)
+	n := 0
+	f.results["createPage"] = func(map[string]string) any {
+		n++
+		return Page{Path: fmt.Sprintf("T-%d", n), URL: fmt.Sprintf("https://telegra.ph/T-%d", n)}
+	}
+	f.results["editPage"] = func(p map[string]string) any { return Page{Path: strings.TrimPrefix(p["path"], "/editPage/")} }
+
+	urls, err := f.client().CreateImagePages(context.Background(), "T", []string{"a.jpg", "b.jpg", "c.jpg"}, SplitOptions{MaxImages: 1})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if !reflect.DeepEqual(urls, []string{"https://telegra.ph/T-1", "https://telegra.ph/T-2", "https://telegra.ph/T-3"}) {
+		t.Fatalf("urls = %v", urls)
+	}
+	// 3 createPage + 2 editPage adding "next" links
+	if len(f.calls) != 5 {
+		t.Fatalf("calls = %d", len(f.calls))
+	}
+	second := f.calls[4]
+	if second["path"] != "/editPage/T-2" || second["title"] != "T (часть 2/3)" ||
+		!strings.Contains(second["content"], "https://telegra.ph/T-1") || !strings.Contains(second["content"], "https://telegra.ph/T-3") {
+		t.Errorf("part 2 edit = %v", second)
+	}
+}
+
+func TestCreateArticleReturnsCreatedPartsOnError(t *testing.T) {
+	f := newFakeServer(t)
+	n := 0
+	f.results["createPage"] = func(map[string]string) any {
+		n++
+		if n == 2 {
+			f.errors["createPage"] = "FLOOD_WAIT_5" // третья часть упирается в лимит
+		}
+		return Page{Path: fmt.Sprintf("T-%d", n), URL: fmt.Sprintf("https://telegra.ph/T-%d", n)}
+	}
+
+	urls, err := f.client().CreateImagePages(context.Background(), "T", []string{"a.jpg", "b.jpg", "c.jpg"}, SplitOptions{MaxImages: 1})
+	if err == nil || appErr.RetryAfterOf(err) != 5*time.Second {
+		t.Fatalf("want FLOOD_WAIT error, got %v", err)
+	}
+	if !reflect.DeepEqual(urls, []string{"https://telegra.ph/T-1", "https://telegra.ph/T-2"}) {
+		t.Fatalf("urls = %v", urls)
+	}
+}
+
+func TestEditImagePagesKeepsExistingPaths(t *testing.T) {
+	f := newFakeServer(t)
+	n := 1
+	f.results["createPage"] = func(map[string]string) any {
+		n++
+		return Page{Path: fmt.Sprintf("T-%d", n), URL: fmt.Sprintf("https://telegra.ph/T-%d", n)}
+	}
+	f.results["editPage"] = func(p map[string]string) any { return Page{Path: strings.TrimPrefix(p["path"], "/editPage/")} }
+
+	// было 1 часть, стало 3: первая редактируется на месте
+	urls, err := f.client().EditImagePages(context.Background(), "T", []string{"https://telegra.ph/T-1"}, []string{"a.jpg", "b.jpg", "c.jpg"}, SplitOptions{MaxImages: 1})
+	if err != nil {
+		t.Fatalf("edit: %v", err)
+	}
+	if !reflect.DeepEqual(urls, []string{"https://telegra.ph/T-1", "https://telegra.ph/T-2", "https://telegra.ph/T-3"}) {
+		t.Fatalf("urls = %v", urls)
+	}
+	// 2 createPage + editPage частей 1 и 2 (третья создана уже со ссылкой назад)
+	if len(f.calls) != 4 {
+		t.Fatalf("calls = %d", len(f.calls))
+	}
+	first := f.calls[2]
+	if first["path"] != "/editPage/T-1" || first["title"] != "T (часть 1/3)" || !strings.Contains(first["content"], "https://telegra.ph/T-2") {
+		t.Errorf("part 1 edit = %v", first)
+	}
+
+	// стало снова 1 часть: лишние страницы ведут на первую
+	f.calls = nil
+	urls, err = f.client().EditImagePages(context.Background(), "T", urls, []string{"a.jpg"}, SplitOptions{MaxImages: 1})
+	if err != nil {
+		t.Fatalf("shrink: %v", err)
+	}
+	if !reflect.DeepEqual(urls, []string{"https://telegra.ph/T-1"}) || len(f.calls) != 3 {
+		t.Fatalf("urls = %v, calls = %d", urls, len(f.calls))
+	}
+	if f.calls[0]["title"] != "T" || f.calls[2]["path"] != "/editPage/T-3" || !strings.Contains(f.calls[2]["content"], "https://telegra.ph/T-1") {
+		t.Errorf("calls = %v", f.calls)
+	}
+}
+
+func TestEditArticleReturnsCreatedPartsOnError(t *testing.T) {
+	f := newFakeServer(t)
+	n := 1
+	f.results["createPage"] = func(map[string]string) any {
+		n++
+		if n == 2 {
+			f.errors["createPage"] = "FLOOD_WAIT_5" // третья часть упирается в лимит
+		}
+		return Page{Path: fmt.Sprintf("T-%d", n), URL: fmt.Sprintf("https://telegra.ph/T-%d", n)}
+	}
+
+	urls, err := f.client().EditImagePages(context.Background(), "T", []string{"https://telegra.ph/T-1"}, []string{"a.jpg", "b.jpg", "c.jpg"}, SplitOptions{MaxImages: 1})
+	if err == nil || appErr.RetryAfterOf(err) != 5*time.Second {
+		t.Fatalf("want FLOOD_WAIT error, got %v", err)
+	}
+	if !reflect.DeepEqual(urls, []string{"https://telegra.ph/T-1", "https://telegra.ph/T-2"}) {
+		t.Fatalf("urls = %v", urls)
+	}
+}
+
+func TestUploadResolvesSrc(t *testing.T) {
+	var gotType, gotName string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, h, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("form file: %v", err)
+			return
+		}
+		f.Close()
+		gotType, gotName = h.Header.Get("Content-Type"), h.Filename
+		fmt.Fprint(w, `[{"src":"/file/abc.jpg"}]`)
+	}))
+	defer srv.Close()
+	c := NewClient(Config{UploadURL: srv.URL + "/upload"})
+
+	u, err := c.Upload(context.Background(), "1/001.jpg", "image/jpeg", []byte{0xff, 0xd8, 0xff})
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	if u != srv.URL+"/file/abc.jpg" {
+		t.Errorf("url = %q", u)
+	}
+	if gotType != "image/jpeg" || gotName != "001.jpg" {
+		t.Errorf("part type=%q name=%q", gotType, gotName)
+	}
+}
+
+func TestUploadErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"error":"File type invalid"}`)
+	}))
+	defer srv.Close()
+	c := NewClient(Config{UploadURL: srv.URL})
+
+	_, err := c.Upload(context.Background(), "a.png", "image/png", []byte("x"))
+	if err == nil || appErr.IsTransient(err) || !strings.Contains(err.Error(), "File type invalid") {
+		t.Errorf("err = %v", err)
+	}
+	if _, err := c.Upload(context.Background(), "a.webp", "image/webp", []byte("x")); err == nil {
+		t.Error("webp accepted")
+	}
+}
+
+func TestBuildArticleLayout(t *testing.T) {
+	a := BuildArticle(PageMeta{
+		Title:        "Летние каникулы",
+		Series:       "Оригинальные работы",
+		Authors:      []string{"A", "B"},
+		Tags:         []string{"Big Breasts", "x-ray"},
+		SourceURL:    "https://www.h-chan.me/manga/1",
+		TagURL:       "https://t.me/s/chan?q=%23{tag}",
+		SubscribeURL: "https://t.me/chan",
+	}, []string{"https://img/1.jpg", "https://img/2.jpg"})
+
+	if !reflect.DeepEqual(a.Images, []string{"https://img/2.jpg"}) {
+		t.Errorf("images = %v", a.Images)
+	}
+	header, _ := json.Marshal(a.Header)
+	for _, want := range []string{
+		`{"tag":"figure","children":[{"tag":"img","attrs":{"src":"https://img/1.jpg"}},{"tag":"figcaption","children":["Летние каникулы"]}]}`,
+		`"Автор: "]},"A, B"`,
+		`{"tag":"a","attrs":{"href":"https://t.me/s/chan?q=%23big_breasts"},"children":["#big_breasts"]}`,
+		`"#x_ray"`,
+		`{"tag":"a","attrs":{"href":"https://www.h-chan.me/manga/1"},"children":["h-chan.me"]}`,
+	} {
+		if !strings.Contains(string(header), want) {
+			t.Errorf("header lacks %s\n%s", want, header)
+		}
+	}
+	footer, _ := json.Marshal(a.Footer)
+	if !strings.Contains(string(footer), `"https://t.me/chan"`) {
+		t.Errorf("footer = %s", footer)
+	}
+
+	// шапка только в первой части, подвал — в последней
+	parts := splitImages([]string{"x", "y"}, SplitOptions{MaxImages: 1}, a.reserve())
+	first, _ := json.Marshal(partContent(a, parts[0], 0, 2, "", "n"))
+	last, _ := json.Marshal(partContent(a, parts[1], 1, 2, "p", ""))
+	if !strings.Contains(string(first), "figure") || strings.Contains(string(first), "t.me/chan\"") {
+		t.Errorf("first part = %s", first)
+	}
+	if strings.Contains(string(last), "figure") || !strings.Contains(string(last), `"https://t.me/chan"`) {
+		t.Errorf("last part = %s", last)
+	}
+}
+
+func TestWithAccountUsesOwnToken(t *testing.T) {
+	f := newFakeServer(t)
+	f.results["createPage"] = Page{URL: "https://telegra.ph/T"}
+	base := f.client()
+
+	if _, err := base.WithAccount("tok2", "", "").CreateImagePage(context.Background(), "T", []string{"a"}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	p := f.params["createPage"]
+	if p["access_token"] != "tok2" || p["author_name"] != "Niko" {
+		t.Errorf("params = %v", p)
+	}
+	if base.AccessToken() != "tok" {
+		t.Errorf("base token changed to %q", base.AccessToken())
+	}
+}
@@ -0,0 +1,103 @@
+package telegraph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+
+	appErr "go_scripts/internal/errors"
+)
+
+const (
+	DefaultUploadURL = "https://telegra.ph/upload"
+	// MaxUploadSize — предел размера файла для /upload.
+	MaxUploadSize = 5 << 20
+)
+
+// uploadTypes — типы файлов, которые принимает /upload.
+var uploadTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"video/mp4":  true,
+}
+
+// CanUpload сообщает, примет ли /upload файл такого типа.
+func CanUpload(contentType string) bool { return uploadTypes[contentType] }
+
+// Upload загружает файл на telegra.ph и возвращает его абсолютный URL.
+// Эндпоинт не входит в API и отвечает [{"src":"/file/..."}] или {"error":"..."}.
+func (c *Client) Upload(ctx context.Context, name, contentType string, data []byte) (string, error) {
+	if len(data) > MaxUploadSize {
+		return "", appErr.NewAppError(appErr.ErrorTypeValidation, fmt.Sprintf("файл %s больше %d байт", name, MaxUploadSize), nil)
+	}
+	if !CanUpload(contentType) {
+		return "", appErr.NewAppError(appErr.ErrorTypeValidation, "telegraph не принимает "+contentType, nil)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, strings.ReplaceAll(name, `"`, "")))
+	h.Set("Content-Type", contentType)
+	part, err := mw.CreatePart(h)
+	if err != nil {
+		return "", appErr.NewInternalError("telegraph upload", err)
+	}
+	_, _ = part.Write(data)
+	if err := mw.Close(); err != nil {
+		return "", appErr.NewInternalError("telegraph upload", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.uploadURL, &body)
+	if err != nil {
+		return "", appErr.NewInternalError("telegraph upload", err)
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", appErr.NewNetworkError("telegraph upload", err).WithTransient(true)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+		return "", appErr.NewNetworkError("telegraph upload", fmt.Errorf("%s", resp.Status)).WithTransient(true)
+	}
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return "", appErr.NewNetworkError("telegraph upload decode", err).WithTransient(true)
+	}
+	var files []struct {
+		Src string `json:"src"`
+	}
+	if err := json.Unmarshal(raw, &files); err != nil || len(files) == 0 || files[0].Src == "" {
+		var e struct {
+			Error string `json:"error"`
+		}
+		_ = json.Unmarshal(raw, &e)
+		if e.Error == "" {
+			e.Error = "unexpected response " + string(raw)
+		}
+		return "", apiError(e.Error)
+	}
+	return c.resolveUploaded(files[0].Src), nil
+}
+
+// resolveUploaded превращает относительный src из ответа /upload в абсолютный URL.
+func (c *Client) resolveUploaded(src string) string {
+	base, err := url.Parse(c.uploadURL)
+	if err != nil {
+		return src
+	}
+	ref, err := url.Parse(src)
+	if err != nil {
+		return src
+	}
+	return base.ResolveReference(ref).String()
+}